@@ -37,7 +37,7 @@ func getExamples() []Example {
 					},
 					"Example2": {
 						"Type": "Task",
-						"Resource": "example:workflow:ExampleSubworkflow",
+						"Resource": "cadence:workflow:example:workflow:ExampleSubworkflow.sync",
 						"End": true
 					}
 				}
@@ -77,6 +77,18 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	var h common.CadenceHelper
+	h.SetupServiceConfig()
+
+	workflowClient, err := h.Builder.BuildCadenceClient()
+	if err != nil {
+		panic(fmt.Errorf("error building cadence client %w", err))
+	}
+
+	// Heartbeat automatically so activities can run longer than the heartbeat timeout, progress is
+	// reported to the workflow with the client
+	activityFunc := aslworkflow.HeartbeatActivity(ExampleActivity, aslworkflow.HeartbeatOptions{Client: workflowClient})
+
 	for _, example := range getExamples() {
 		sm, err := aslworkflow.FromJSON([]byte(example.json))
 		if err != nil {
@@ -84,15 +96,14 @@ func main() {
 		}
 
 		sm.RegisterWorkflow(example.name)
-		sm.RegisterActivities(ExampleActivity)
+		if err := sm.RegisterActivities(activityFunc); err != nil {
+			panic(fmt.Errorf("error registering activities %w", err))
+		}
 	}
 
 	// Register the Global Task Handler
 	aslworkflow.RegisterHandler(ExampleTaskHandler)
 
-	var h common.CadenceHelper
-	h.SetupServiceConfig()
-
 	// Configure worker options.
 	workerOptions := worker.Options{
 		MetricsScope: h.Scope,
@@ -106,20 +117,16 @@ func main() {
 // ActivityPrefix is the prefix used for specifying activities to run (could also be something like `arn:aws:...`
 var ActivityPrefix = "example:activity:"
 
-// WorkflowPrefix is the prefix used for specifying subworkflow to run (could also be something like `arn:aws:...`
-var WorkflowPrefix = "example:workflow:"
-
 var ErrUnknownResource = errors.New("unknown resource")
 
-// ExampleTaskHandler is called for each task, it decides what to do. In this example it will execute and activity or subworkflow
+// ExampleTaskHandler is called for each task, it decides what to do. In this example it will execute an activity,
+// subworkflows are run by the built in cadence:workflow: resource
 func ExampleTaskHandler(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
 	var result interface{}
 	var err error
 
 	if strings.HasPrefix(resource, ActivityPrefix) {
 		err = workflow.ExecuteActivity(ctx, resource, input).Get(ctx, &result)
-	} else if strings.HasPrefix(resource, WorkflowPrefix) {
-		err = workflow.ExecuteChildWorkflow(ctx, resource, input).Get(ctx, &result)
 	} else {
 		return nil, ErrUnknownResource
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 
+	"github.com/checkr/states-language-cadence/pkg/aslworkflow"
 	"github.com/uber-go/tally"
 	"go.uber.org/cadence/.gen/go/cadence/workflowserviceclient"
 	"go.uber.org/cadence/client"
@@ -152,3 +153,48 @@ func (h *CadenceHelper) CancelWorkflow(workflowID string) {
 		panic("Failed to cancel workflow.")
 	}
 }
+
+// SendTaskSuccess completes the Task state waiting on the task token with the output
+func (h *CadenceHelper) SendTaskSuccess(token string, output interface{}) {
+	workflowClient, err := h.Builder.BuildCadenceClient()
+	if err != nil {
+		h.Logger.Error("Failed to build cadence client.", zap.Error(err))
+		panic(err)
+	}
+
+	err = aslworkflow.SendTaskSuccess(context.Background(), workflowClient, token, output)
+	if err != nil {
+		h.Logger.Error("Failed to send task success", zap.Error(err))
+		panic("Failed to send task success.")
+	}
+}
+
+// SendTaskFailure fails the Task state waiting on the task token with the error name and cause
+func (h *CadenceHelper) SendTaskFailure(token, errorName, cause string) {
+	workflowClient, err := h.Builder.BuildCadenceClient()
+	if err != nil {
+		h.Logger.Error("Failed to build cadence client.", zap.Error(err))
+		panic(err)
+	}
+
+	err = aslworkflow.SendTaskFailure(context.Background(), workflowClient, token, errorName, cause)
+	if err != nil {
+		h.Logger.Error("Failed to send task failure", zap.Error(err))
+		panic("Failed to send task failure.")
+	}
+}
+
+// SendTaskHeartbeat keeps the Task state waiting on the task token from timing out
+func (h *CadenceHelper) SendTaskHeartbeat(token string) {
+	workflowClient, err := h.Builder.BuildCadenceClient()
+	if err != nil {
+		h.Logger.Error("Failed to build cadence client.", zap.Error(err))
+		panic(err)
+	}
+
+	err = aslworkflow.SendTaskHeartbeat(context.Background(), workflowClient, token)
+	if err != nil {
+		h.Logger.Error("Failed to send task heartbeat", zap.Error(err))
+		panic("Failed to send task heartbeat.")
+	}
+}
@@ -2,6 +2,10 @@ package aslworkflow
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
 
 	"go.uber.org/cadence/activity"
 )
@@ -11,3 +15,128 @@ type Activity func(ctx context.Context, input interface{}) (interface{}, error)
 func RegisterActivity(activityName string, activityFunc Activity) {
 	activity.RegisterWithOptions(activityFunc, activity.RegisterOptions{Name: activityName})
 }
+
+//
+// Typed activities are functions of the form func(context.Context, In) (Out, error), where In and Out are
+// any types that can be converted to and from JSON. The adapter converts the state's input into In and Out
+// back into a JSON value, so activities don't need to decode maps themselves.
+//
+//	type Order struct {
+//		ID    string
+//		Items []string
+//	}
+//
+//	func ShipOrder(ctx context.Context, order Order) (*Shipment, error)
+//
+//	err := sm.RegisterResourceActivities(map[string]interface{}{
+//		"example:activity:ShipOrder": ShipOrder,
+//	})
+//
+// Input that cannot be converted into In, or an Out that cannot be converted to JSON, fails the state
+// with States.Runtime.
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// TypedActivity returns an Activity that calls a func(context.Context, In) (Out, error). An Activity is
+// returned as is.
+func TypedActivity(activityFunc interface{}) (Activity, error) {
+	switch f := activityFunc.(type) {
+	case Activity:
+		return f, nil
+	case func(context.Context, interface{}) (interface{}, error):
+		return f, nil
+	}
+
+	if activityFunc == nil {
+		return nil, fmt.Errorf("activity must be a func(context.Context, In) (Out, error), not nil")
+	}
+
+	fn := reflect.ValueOf(activityFunc)
+	fnType := fn.Type()
+
+	if fnType.Kind() != reflect.Func || fnType.NumIn() != 2 || fnType.NumOut() != 2 ||
+		fnType.In(0) != contextType || fnType.Out(1) != errorType {
+		return nil, fmt.Errorf("activity must be a func(context.Context, In) (Out, error), not %v", fnType)
+	}
+
+	inType := fnType.In(1)
+
+	return func(ctx context.Context, input interface{}) (interface{}, error) {
+		in := reflect.New(inType)
+		if err := convertJSON(input, in.Interface()); err != nil {
+			return nil, newStatesError(StatesRuntime, fmt.Sprintf("cannot convert input to %v: %v", inType, err))
+		}
+
+		results := fn.Call([]reflect.Value{reflect.ValueOf(ctx), in.Elem()})
+		if err, _ := results[1].Interface().(error); err != nil {
+			return nil, err
+		}
+
+		var output interface{}
+		if err := convertJSON(results[0].Interface(), &output); err != nil {
+			return nil, newStatesError(StatesRuntime, fmt.Sprintf("cannot convert output of %v to JSON: %v", fnType, err))
+		}
+		return output, nil
+	}, nil
+}
+
+// RegisterTypedActivity registers a typed activity for the resource, it is also used as the local activity
+// for Tasks run with LocalActivity
+func RegisterTypedActivity(resource string, activityFunc interface{}) error {
+	adapted, err := TypedActivity(activityFunc)
+	if err != nil {
+		return fmt.Errorf("%v: %w", resource, err)
+	}
+
+	RegisterActivity(resource, adapted)
+	RegisterLocalActivity(resource, adapted)
+	return nil
+}
+
+// convertJSON copies value into target by encoding it as JSON
+func convertJSON(value interface{}, target interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
+
+// startHeartbeats calls tick at half of the activity's heartbeat timeout until stopped, by default tick
+// records a heartbeat without details
+func startHeartbeats(ctx context.Context, tick func()) func() {
+	if tick == nil {
+		tick = func() { activity.RecordHeartbeat(ctx) }
+	}
+
+	timeout := activity.GetInfo(ctx).HeartbeatTimeout
+	if timeout <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(timeout / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				tick()
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
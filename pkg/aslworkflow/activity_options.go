@@ -0,0 +1,207 @@
+package aslworkflow
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/cadence"
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// Task states can route the activities their resource runs, e.g. to workers on a separate task list.
+// The options are applied to the context the handler receives, so they are used by any activity the
+// handler executes with it, as well as the http:invoke and exec:command integrations.
+//
+//	"Resize": {
+//		"Type": "Task",
+//		"Resource": "example:activity:Resize",
+//		"TaskList": "high-memory",
+//		"ScheduleToStartTimeoutSeconds": 600,
+//		"WaitForCancellation": true,
+//		"RetryPolicy": {
+//			"InitialIntervalSeconds": 1,
+//			"BackoffCoefficient": 2.0,
+//			"MaximumAttempts": 5,
+//			"NonRetriableErrorReasons": ["Resize.InvalidImage"]
+//		},
+//		"End": true
+//	}
+//
+// Defaults for resources can be set on a ResourceRegistry, options set on the state take precedence.
+//
+//	registry.SetActivityOptions("example:activity:Resize*", aslworkflow.ActivityOptions{TaskList: to.Strp("high-memory")})
+//
+// The RetryPolicy retries the activity within Cadence, a Retry on the state only sees the error once
+// the policy gives up.
+
+type ActivityOptions struct {
+	TaskList                      *string              `json:",omitempty"`
+	ScheduleToStartTimeoutSeconds int                  `json:",omitempty"`
+	WaitForCancellation           *bool                `json:",omitempty"`
+	RetryPolicy                   *ActivityRetryPolicy `json:",omitempty"`
+	LocalActivity                 *bool                `json:",omitempty"` // See RegisterLocalActivity
+}
+
+// ActivityRetryPolicy is a cadence.RetryPolicy with intervals in seconds
+type ActivityRetryPolicy struct {
+	InitialIntervalSeconds    int
+	BackoffCoefficient        float64 `json:",omitempty"` // Defaults to 2.0
+	MaximumIntervalSeconds    int     `json:",omitempty"` // Defaults to 100x InitialIntervalSeconds
+	ExpirationIntervalSeconds int     `json:",omitempty"`
+	MaximumAttempts           int32   `json:",omitempty"`
+
+	NonRetriableErrorReasons []string `json:",omitempty"`
+}
+
+// isLocalActivity returns true if the resource is run as a local activity
+func (o ActivityOptions) isLocalActivity() bool {
+	return o.LocalActivity != nil && *o.LocalActivity
+}
+
+// merge returns the options with any unset fields taken from the defaults
+func (o ActivityOptions) merge(defaults ActivityOptions) ActivityOptions {
+	if o.TaskList == nil {
+		o.TaskList = defaults.TaskList
+	}
+	if o.ScheduleToStartTimeoutSeconds == 0 {
+		o.ScheduleToStartTimeoutSeconds = defaults.ScheduleToStartTimeoutSeconds
+	}
+	if o.WaitForCancellation == nil {
+		o.WaitForCancellation = defaults.WaitForCancellation
+	}
+	if o.RetryPolicy == nil {
+		o.RetryPolicy = defaults.RetryPolicy
+	}
+	if o.LocalActivity == nil {
+		o.LocalActivity = defaults.LocalActivity
+	}
+	return o
+}
+
+// apply sets the options on the Cadence activity options
+func (o ActivityOptions) apply(ao *workflow.ActivityOptions) {
+	if o.TaskList != nil {
+		ao.TaskList = *o.TaskList
+	}
+	if o.ScheduleToStartTimeoutSeconds > 0 {
+		ao.ScheduleToStartTimeout = time.Duration(o.ScheduleToStartTimeoutSeconds) * time.Second
+	}
+	if o.WaitForCancellation != nil {
+		ao.WaitForCancellation = *o.WaitForCancellation
+	}
+	if o.RetryPolicy != nil {
+		ao.RetryPolicy = o.RetryPolicy.cadence()
+	}
+}
+
+func (o ActivityOptions) Validate() error {
+	if o.TaskList != nil && *o.TaskList == "" {
+		return fmt.Errorf("TaskList must not be empty")
+	}
+
+	if o.ScheduleToStartTimeoutSeconds < 0 {
+		return fmt.Errorf("ScheduleToStartTimeoutSeconds must be positive")
+	}
+
+	if o.RetryPolicy != nil {
+		return o.RetryPolicy.Validate()
+	}
+
+	return nil
+}
+
+func (p *ActivityRetryPolicy) cadence() *cadence.RetryPolicy {
+	backoff := p.BackoffCoefficient
+	if backoff == 0 {
+		backoff = 2.0
+	}
+
+	return &cadence.RetryPolicy{
+		InitialInterval:          time.Duration(p.InitialIntervalSeconds) * time.Second,
+		BackoffCoefficient:       backoff,
+		MaximumInterval:          time.Duration(p.MaximumIntervalSeconds) * time.Second,
+		ExpirationInterval:       time.Duration(p.ExpirationIntervalSeconds) * time.Second,
+		MaximumAttempts:          p.MaximumAttempts,
+		NonRetriableErrorReasons: p.NonRetriableErrorReasons,
+	}
+}
+
+// Validate checks the policy the same way Cadence does when the activity is scheduled
+func (p *ActivityRetryPolicy) Validate() error {
+	if p.InitialIntervalSeconds <= 0 {
+		return fmt.Errorf("RetryPolicy InitialIntervalSeconds must be greater than 0")
+	}
+
+	if p.MaximumIntervalSeconds < 0 || p.ExpirationIntervalSeconds < 0 || p.MaximumAttempts < 0 {
+		return fmt.Errorf("RetryPolicy MaximumIntervalSeconds, ExpirationIntervalSeconds and MaximumAttempts must be positive")
+	}
+
+	if p.BackoffCoefficient != 0 && p.BackoffCoefficient < 1 {
+		return fmt.Errorf("RetryPolicy BackoffCoefficient must be at least 1.0")
+	}
+
+	if p.MaximumAttempts == 0 && p.ExpirationIntervalSeconds == 0 {
+		return fmt.Errorf("RetryPolicy requires MaximumAttempts or ExpirationIntervalSeconds")
+	}
+
+	return nil
+}
+
+const activityOptionsContextKey contextKey = "aslActivityOptions"
+
+// defaultActivityOptions are used for every activity unless a Task state changes them
+func defaultActivityOptions() workflow.ActivityOptions {
+	return workflow.ActivityOptions{
+		ScheduleToStartTimeout: time.Minute,
+		StartToCloseTimeout:    time.Minute,
+		HeartbeatTimeout:       time.Second * 20,
+	}
+}
+
+// withTaskActivityOptions sets the options of a Task state, and its timeouts that are not 0, on the
+// activity options of the context. Only those fields change, so options set by a workflow that calls
+// StateMachine.Execute itself are kept, unless there is a RetryPolicy: Cadence can only set it by
+// replacing all of the options, with the ones this package tracked.
+func withTaskActivityOptions(ctx workflow.Context, o ActivityOptions, startToClose time.Duration, heartbeat time.Duration) workflow.Context {
+	ao := getActivityOptions(ctx)
+
+	if startToClose > 0 {
+		ao.StartToCloseTimeout = startToClose
+		ctx = workflow.WithStartToCloseTimeout(ctx, startToClose)
+	}
+	if heartbeat > 0 {
+		ao.HeartbeatTimeout = heartbeat
+		ctx = workflow.WithHeartbeatTimeout(ctx, heartbeat)
+	}
+
+	if o.TaskList != nil {
+		ctx = workflow.WithTaskList(ctx, *o.TaskList)
+	}
+	if o.ScheduleToStartTimeoutSeconds > 0 {
+		ctx = workflow.WithScheduleToStartTimeout(ctx, time.Duration(o.ScheduleToStartTimeoutSeconds)*time.Second)
+	}
+	if o.WaitForCancellation != nil {
+		ctx = workflow.WithWaitForCancellation(ctx, *o.WaitForCancellation)
+	}
+
+	o.apply(&ao)
+	if o.RetryPolicy != nil {
+		ctx = workflow.WithActivityOptions(ctx, ao)
+	}
+	return workflow.WithValue(ctx, activityOptionsContextKey, ao)
+}
+
+// withActivityOptions sets the activity options, keeping a copy because Cadence does not expose the
+// options of a context and a RetryPolicy can only be set by replacing all of them
+func withActivityOptions(ctx workflow.Context, ao workflow.ActivityOptions) workflow.Context {
+	ctx = workflow.WithActivityOptions(ctx, ao)
+	return workflow.WithValue(ctx, activityOptionsContextKey, ao)
+}
+
+func getActivityOptions(ctx workflow.Context) workflow.ActivityOptions {
+	if ao, ok := ctx.Value(activityOptionsContextKey).(workflow.ActivityOptions); ok {
+		return ao
+	}
+	return defaultActivityOptions()
+}
@@ -0,0 +1,202 @@
+package aslworkflow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/cadence/activity"
+	"go.uber.org/cadence/workflow"
+)
+
+var registerFlakyActivityOnce sync.Once
+
+// flakyActivity fails until its third attempt and returns the task list it ran on
+func flakyActivity(ctx context.Context, input interface{}) (interface{}, error) {
+	info := activity.GetInfo(ctx)
+	if info.Attempt < 2 {
+		return nil, errors.New("flaky")
+	}
+	return map[string]interface{}{"taskList": info.TaskList, "attempt": info.Attempt}, nil
+}
+
+var activityOptionsMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "example:activity:Flaky",
+			"ScheduleToStartTimeoutSeconds": 600,
+			"RetryPolicy": {
+				"InitialIntervalSeconds": 1,
+				"MaximumAttempts": 3
+			},
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Task_Activity_Options() {
+	registerFlakyActivityOnce.Do(func() {
+		RegisterActivity("example:activity:Flaky", flakyActivity)
+	})
+
+	sm, err := FromJSON(activityOptionsMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		var result interface{}
+		err := workflow.ExecuteActivity(ctx, resource, input).Get(ctx, &result)
+		return result, err
+	}
+
+	registry := NewResourceRegistry()
+	registry.RegisterPrefix("example:activity:", handler)
+	registry.SetActivityOptionsPrefix("example:activity:", ActivityOptions{
+		TaskList:                      to.Strp("high-memory"),
+		ScheduleToStartTimeoutSeconds: 60,
+	})
+
+	s.NoError(RegisterWorkflowWithRegistry("TestTaskActivityOptionsWorkflow", *sm, registry))
+	s.env.ExecuteWorkflow("TestTaskActivityOptionsWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal("high-memory", result["taskList"])
+	s.Equal(2.0, result["attempt"])
+}
+
+var registerActivityInfoOnce sync.Once
+
+// activityInfoActivity returns the timeouts the activity was scheduled with
+func activityInfoActivity(ctx context.Context, input interface{}) (interface{}, error) {
+	info := activity.GetInfo(ctx)
+	return map[string]interface{}{
+		"startToClose": info.Deadline.Sub(info.StartedTimestamp).String(),
+		"heartbeat":    info.HeartbeatTimeout.String(),
+		"taskList":     info.TaskList,
+	}, nil
+}
+
+var callerActivityOptionsMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "example:activity:TestActivityInfo",
+			"HeartbeatSeconds": 30,
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Execute_Keeps_Caller_Activity_Options() {
+	registerActivityInfoOnce.Do(func() {
+		RegisterActivity("example:activity:TestActivityInfo", activityInfoActivity)
+	})
+
+	sm, err := FromJSON(callerActivityOptionsMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		var result interface{}
+		err := workflow.ExecuteActivity(ctx, resource, input).Get(ctx, &result)
+		return result, err
+	}
+	RegisterHandler(handler)
+	defer DeregisterHandler()
+
+	// A workflow of its own that runs the machine with Execute
+	workflowFunc := func(ctx workflow.Context, input interface{}) (interface{}, error) {
+		ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+			TaskList:               "caller",
+			ScheduleToStartTimeout: time.Minute,
+			StartToCloseTimeout:    time.Hour,
+		})
+		return sm.Execute(ctx, input)
+	}
+	workflow.RegisterWithOptions(workflowFunc, workflow.RegisterOptions{Name: "TestExecuteCallerActivityOptionsWorkflow"})
+
+	s.env.ExecuteWorkflow("TestExecuteCallerActivityOptionsWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	// The state only sets the heartbeat, the rest comes from the caller
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{"startToClose": "1h0m0s", "heartbeat": "30s", "taskList": "caller"}, result)
+}
+
+func TestActivityOptionsMerge(t *testing.T) {
+	defaults := ActivityOptions{
+		TaskList:                      to.Strp("default"),
+		ScheduleToStartTimeoutSeconds: 60,
+		WaitForCancellation:           to.Boolp(true),
+	}
+
+	options := ActivityOptions{TaskList: to.Strp("state")}.merge(defaults)
+	assert.Equal(t, "state", *options.TaskList)
+	assert.Equal(t, 60, options.ScheduleToStartTimeoutSeconds)
+	assert.True(t, *options.WaitForCancellation)
+	assert.Nil(t, options.RetryPolicy)
+
+	ao := defaultActivityOptions()
+	options.apply(&ao)
+	assert.Equal(t, "state", ao.TaskList)
+	assert.True(t, ao.WaitForCancellation)
+	assert.Nil(t, ao.RetryPolicy)
+}
+
+func TestActivityOptionsValidate(t *testing.T) {
+	tests := []struct {
+		options ActivityOptions
+		err     string
+	}{
+		{ActivityOptions{}, ""},
+		{ActivityOptions{TaskList: to.Strp("")}, "TaskList must not be empty"},
+		{ActivityOptions{ScheduleToStartTimeoutSeconds: -1}, "ScheduleToStartTimeoutSeconds must be positive"},
+		{ActivityOptions{RetryPolicy: &ActivityRetryPolicy{MaximumAttempts: 3}}, "RetryPolicy InitialIntervalSeconds must be greater than 0"},
+		{ActivityOptions{RetryPolicy: &ActivityRetryPolicy{InitialIntervalSeconds: 1}}, "RetryPolicy requires MaximumAttempts or ExpirationIntervalSeconds"},
+		{ActivityOptions{RetryPolicy: &ActivityRetryPolicy{InitialIntervalSeconds: 1, MaximumAttempts: 3, BackoffCoefficient: 0.5}}, "RetryPolicy BackoffCoefficient must be at least 1.0"},
+		{ActivityOptions{RetryPolicy: &ActivityRetryPolicy{InitialIntervalSeconds: 1, ExpirationIntervalSeconds: 60}}, ""},
+	}
+
+	for _, test := range tests {
+		err := test.options.Validate()
+		if test.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, test.err)
+		}
+	}
+}
+
+func TestStateMachineSetRegistryActivityOptions(t *testing.T) {
+	sm, err := FromJSON(taskMachine)
+	assert.NoError(t, err)
+
+	registry := NewResourceRegistry()
+	registry.RegisterPrefix("arn:aws:", namedHandler("aws"))
+	registry.SetActivityOptions("arn:aws:resource:*", ActivityOptions{ScheduleToStartTimeoutSeconds: -1})
+
+	err = sm.SetRegistry(registry)
+	assert.EqualError(t, err, "invalid state machine:\nTaskState(Example1) Error: Resource \"arn:aws:resource:example\" default ActivityOptions: ScheduleToStartTimeoutSeconds must be positive")
+}
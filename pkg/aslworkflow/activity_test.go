@@ -0,0 +1,189 @@
+package aslworkflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/cadence"
+	"go.uber.org/cadence/workflow"
+)
+
+type testOrder struct {
+	ID    string
+	Items []string
+}
+
+type testShipment struct {
+	OrderID string `json:"orderId"`
+	Count   int    `json:"count"`
+}
+
+func shipOrder(ctx context.Context, order testOrder) (*testShipment, error) {
+	if order.ID == "" {
+		return nil, errors.New("missing order ID")
+	}
+	return &testShipment{OrderID: order.ID, Count: len(order.Items)}, nil
+}
+
+var typedActivityMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "example:activity:TestShipOrder",
+			"Parameters": {
+				"ID.$": "$.orderId",
+				"Items.$": "$.items"
+			},
+			"ResultPath": "$.shipment",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Typed_Activity() {
+	sm, err := FromJSON(typedActivityMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	s.NoError(sm.RegisterResourceActivities(map[string]interface{}{
+		"example:activity:TestShipOrder": shipOrder,
+	}))
+
+	err = sm.RegisterResourceActivities(map[string]interface{}{
+		"example:activity:TestShipOrder": shipOrder,
+	})
+	s.EqualError(err, "example:activity:TestShipOrder: an activity is already registered for the resource")
+
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		var result interface{}
+		err := workflow.ExecuteActivity(ctx, resource, input).Get(ctx, &result)
+		return result, err
+	}
+	RegisterHandler(handler)
+
+	RegisterWorkflow("TestTypedActivityWorkflow", *sm)
+	s.env.ExecuteWorkflow("TestTypedActivityWorkflow", map[string]interface{}{
+		"orderId": "order-1",
+		"items":   []interface{}{"a", "b"},
+	})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{"orderId": "order-1", "count": 2.0}, result["shipment"])
+}
+
+func TestTypedActivity(t *testing.T) {
+	activityFunc, err := TypedActivity(shipOrder)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	output, err := activityFunc(context.Background(), map[string]interface{}{"ID": "order-1", "Items": []interface{}{"a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"orderId": "order-1", "count": 1.0}, output)
+
+	_, err = activityFunc(context.Background(), map[string]interface{}{})
+	assert.EqualError(t, err, "missing order ID")
+
+	_, err = activityFunc(context.Background(), map[string]interface{}{"Items": "not an array"})
+	customErr, ok := err.(*cadence.CustomError)
+	if assert.True(t, ok) {
+		assert.Equal(t, StatesRuntime, customErr.Reason())
+	}
+}
+
+func TestTypedActivityInvalid(t *testing.T) {
+	for _, activityFunc := range []interface{}{
+		nil,
+		"not a function",
+		func(order testOrder) (*testShipment, error) { return nil, nil },
+		func(ctx context.Context, order testOrder) *testShipment { return nil },
+		func(ctx context.Context, order testOrder) (*testShipment, string) { return nil, "" },
+	} {
+		_, err := TypedActivity(activityFunc)
+		assert.Error(t, err)
+	}
+}
+
+func TestRegisterResourceActivitiesUnused(t *testing.T) {
+	sm, err := FromJSON(taskMachine)
+	assert.NoError(t, err)
+
+	err = sm.RegisterResourceActivities(map[string]interface{}{"example:activity:Unused": shipOrder})
+	assert.EqualError(t, err, "example:activity:Unused: resource is not used by the state machine")
+
+	err = sm.RegisterResourceActivities(map[string]interface{}{"arn:aws:resource:example": "not a function"})
+	assert.EqualError(t, err, "arn:aws:resource:example: activity must be a func(context.Context, In) (Out, error), not string")
+}
+
+var resourceActivitiesMachine = []byte(`
+{
+	"StartAt": "First",
+	"States": {
+		"First": {
+			"Type": "Task",
+			"Resource": "example:activity:TestRetryFirst",
+			"Next": "Second"
+		},
+		"Second": {
+			"Type": "Task",
+			"Resource": "example:activity:TestRetrySecond",
+			"End": true
+		}
+	}
+}
+`)
+
+func TestRegisterResourceActivitiesAllOrNothing(t *testing.T) {
+	sm, err := FromJSON(resourceActivitiesMachine)
+	assert.NoError(t, err)
+
+	err = sm.RegisterResourceActivities(map[string]interface{}{
+		"example:activity:TestRetryFirst":  shipOrder,
+		"example:activity:TestRetrySecond": "not a function",
+	})
+	assert.EqualError(t, err, "example:activity:TestRetrySecond: activity must be a func(context.Context, In) (Out, error), not string")
+	assert.False(t, registeredActivities["example:activity:TestRetryFirst"])
+
+	assert.NoError(t, sm.RegisterResourceActivities(map[string]interface{}{
+		"example:activity:TestRetryFirst":  shipOrder,
+		"example:activity:TestRetrySecond": shipOrder,
+	}))
+	assert.True(t, registeredActivities["example:activity:TestRetryFirst"])
+	assert.True(t, registeredActivities["example:activity:TestRetrySecond"])
+}
+
+var typedActivitiesMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "example:activity:TestTypedShared",
+			"End": true
+		}
+	}
+}
+`)
+
+func TestRegisterActivitiesTyped(t *testing.T) {
+	sm, err := FromJSON(typedActivitiesMachine)
+	assert.NoError(t, err)
+
+	err = sm.RegisterActivities("not a function")
+	assert.EqualError(t, err, "activity must be a func(context.Context, In) (Out, error), not string")
+	assert.False(t, registeredActivities["example:activity:TestTypedShared"])
+
+	assert.NoError(t, sm.RegisterActivities(shipOrder))
+	assert.True(t, registeredActivities["example:activity:TestTypedShared"])
+}
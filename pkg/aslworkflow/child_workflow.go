@@ -0,0 +1,222 @@
+package aslworkflow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/checkr/states-language-cadence/pkg/jsonpath"
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// Task states with a cadence:workflow: Resource run the named workflow as a child workflow. With the
+// .sync suffix the state waits for the child to complete and its result is the child's output,
+// otherwise the state only waits for the child to start and its result is the child's IDs:
+//
+//	{"WorkflowId": "...", "RunId": "..."}
+//
+// Failures of the child are States.TaskFailed, or States.Timeout if it times out. ChildWorkflowOptions
+// are optional, by default the child runs on the same domain and task list with the same timeouts as the
+// parent. When the parent closes, a child started with .sync is terminated and other children are
+// abandoned, unless ParentClosePolicy says otherwise.
+//
+//	"StartOrder": {
+//		"Type": "Task",
+//		"Resource": "cadence:workflow:example:workflow:Order.sync",
+//		"ChildWorkflowOptions": {
+//			"TaskList": "orders",
+//			"WorkflowIDPath": "$.orderId",
+//			"ExecutionStartToCloseTimeoutSeconds": 3600,
+//			"ParentClosePolicy": "REQUEST_CANCEL"
+//		},
+//		"End": true
+//	}
+
+const ChildWorkflowResourcePrefix = "cadence:workflow:"
+const childWorkflowSyncSuffix = ".sync"
+
+const ParentClosePolicyTerminate = "TERMINATE"
+const ParentClosePolicyRequestCancel = "REQUEST_CANCEL"
+const ParentClosePolicyAbandon = "ABANDON"
+
+type ChildWorkflowOptions struct {
+	Domain   *string `json:",omitempty"`
+	TaskList *string `json:",omitempty"`
+
+	WorkflowID     *string        `json:",omitempty"`
+	WorkflowIDPath *jsonpath.Path `json:",omitempty"`
+
+	ExecutionStartToCloseTimeoutSeconds int `json:",omitempty"`
+	TaskStartToCloseTimeoutSeconds      int `json:",omitempty"`
+
+	ParentClosePolicy *string `json:",omitempty"`
+}
+
+// parseChildWorkflowResource returns the workflow name of the resource and whether to wait for the result
+func parseChildWorkflowResource(resource string) (name string, sync bool) {
+	name = strings.TrimPrefix(resource, ChildWorkflowResourcePrefix)
+	if strings.HasSuffix(name, childWorkflowSyncSuffix) {
+		return strings.TrimSuffix(name, childWorkflowSyncSuffix), true
+	}
+	return name, false
+}
+
+func executeChildWorkflow(ctx workflow.Context, s *TaskState, resource string, input interface{}) (interface{}, error) {
+	name, sync := parseChildWorkflowResource(resource)
+
+	cwo, err := s.ChildWorkflowOptions.cadenceOptions(ctx, input, sync)
+	if err != nil {
+		return nil, err
+	}
+
+	future := workflow.ExecuteChildWorkflow(workflow.WithChildOptions(ctx, cwo), name, input)
+
+	if !sync {
+		var execution workflow.Execution
+		if err := future.GetChildWorkflowExecution().Get(ctx, &execution); err != nil {
+			return nil, childWorkflowFailed(err)
+		}
+
+		return map[string]interface{}{
+			"WorkflowId": execution.ID,
+			"RunId":      execution.RunID,
+		}, nil
+	}
+
+	var result interface{}
+	if err := future.Get(ctx, &result); err != nil {
+		return nil, childWorkflowFailed(err)
+	}
+	return result, nil
+}
+
+// childWorkflowFailed names the failure of a child workflow States.TaskFailed, keeping the name of the
+// child's error in the cause. Timeouts stay States.Timeout.
+func childWorkflowFailed(err error) error {
+	name := ErrorName(err)
+	if name == StatesTimeout {
+		return err
+	}
+	return newStatesError(StatesTaskFailed, fmt.Sprintf("%v: %v", name, ErrorCause(err)))
+}
+
+// cadenceOptions returns the options the child is started with, defaults come from the parent
+func (o *ChildWorkflowOptions) cadenceOptions(ctx workflow.Context, input interface{}, sync bool) (workflow.ChildWorkflowOptions, error) {
+	info := workflow.GetInfo(ctx)
+
+	cwo := workflow.ChildWorkflowOptions{
+		ExecutionStartToCloseTimeout: time.Duration(info.ExecutionStartToCloseTimeoutSeconds) * time.Second,
+		TaskStartToCloseTimeout:      time.Duration(info.TaskStartToCloseTimeoutSeconds) * time.Second,
+		ChildPolicy:                  workflow.ChildWorkflowPolicyAbandon,
+	}
+
+	if sync {
+		cwo.ChildPolicy = workflow.ChildWorkflowPolicyTerminate
+	}
+
+	if o == nil {
+		return cwo, nil
+	}
+
+	if o.Domain != nil {
+		cwo.Domain = *o.Domain
+	}
+
+	if o.TaskList != nil {
+		cwo.TaskList = *o.TaskList
+	}
+
+	if o.WorkflowID != nil {
+		cwo.WorkflowID = *o.WorkflowID
+	}
+
+	if o.WorkflowIDPath != nil {
+		id, err := getPath(ctx, o.WorkflowIDPath, input)
+		if err != nil {
+			return cwo, fmt.Errorf("WorkflowIDPath Error: %w", err)
+		}
+
+		idStr, ok := id.(string)
+		if !ok {
+			return cwo, fmt.Errorf("WorkflowIDPath must select a string")
+		}
+		cwo.WorkflowID = idStr
+	}
+
+	if o.ExecutionStartToCloseTimeoutSeconds > 0 {
+		cwo.ExecutionStartToCloseTimeout = time.Duration(o.ExecutionStartToCloseTimeoutSeconds) * time.Second
+	}
+
+	if o.TaskStartToCloseTimeoutSeconds > 0 {
+		cwo.TaskStartToCloseTimeout = time.Duration(o.TaskStartToCloseTimeoutSeconds) * time.Second
+	}
+
+	if o.ParentClosePolicy != nil {
+		cwo.ChildPolicy = childPolicy(*o.ParentClosePolicy)
+	}
+
+	return cwo, nil
+}
+
+func childPolicy(parentClosePolicy string) workflow.ChildWorkflowPolicy {
+	switch parentClosePolicy {
+	case ParentClosePolicyRequestCancel:
+		return workflow.ChildWorkflowPolicyRequestCancel
+	case ParentClosePolicyAbandon:
+		return workflow.ChildWorkflowPolicyAbandon
+	}
+	return workflow.ChildWorkflowPolicyTerminate
+}
+
+// isChildWorkflowValid checks the Resource and ChildWorkflowOptions of a Task state
+func (s *TaskState) isChildWorkflowValid() error {
+	if s.Resource == nil {
+		return nil
+	}
+
+	resource, _ := parseTaskResource(*s.Resource)
+	switch {
+	case strings.HasPrefix(resource, ChildWorkflowResourcePrefix):
+		if name, _ := parseChildWorkflowResource(resource); name == "" {
+			return fmt.Errorf("Resource must name a workflow")
+		}
+	case strings.HasPrefix(resource, StateMachineResourcePrefix):
+		// ChildWorkflowOptions run the state machine as a child workflow
+		if strings.TrimPrefix(resource, StateMachineResourcePrefix) == "" {
+			return fmt.Errorf("Resource must name a state machine")
+		}
+	default:
+		if s.ChildWorkflowOptions != nil {
+			return fmt.Errorf("ChildWorkflowOptions require a %v or %v Resource", ChildWorkflowResourcePrefix, StateMachineResourcePrefix)
+		}
+		return nil
+	}
+
+	o := s.ChildWorkflowOptions
+	if o == nil {
+		return nil
+	}
+
+	if o.WorkflowID != nil && o.WorkflowIDPath != nil {
+		return fmt.Errorf("Only One (WorkflowID,WorkflowIDPath)")
+	}
+
+	if err := isReferencePathValid("WorkflowIDPath", o.WorkflowIDPath); err != nil {
+		return err
+	}
+
+	if o.ExecutionStartToCloseTimeoutSeconds < 0 || o.TaskStartToCloseTimeoutSeconds < 0 {
+		return fmt.Errorf("ChildWorkflowOptions timeouts must be positive")
+	}
+
+	if o.ParentClosePolicy != nil {
+		switch *o.ParentClosePolicy {
+		case ParentClosePolicyTerminate, ParentClosePolicyRequestCancel, ParentClosePolicyAbandon:
+		default:
+			return fmt.Errorf("Unknown ParentClosePolicy %q", *o.ParentClosePolicy)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package aslworkflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var childMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Pass",
+			"Result": "child",
+			"ResultPath": "$.from",
+			"End": true
+		}
+	}
+}
+`)
+
+var childFailMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Fail",
+			"Error": "ChildError",
+			"Cause": "the child failed"
+		}
+	}
+}
+`)
+
+var parentMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "cadence:workflow:TestChildWorkflow.sync",
+			"ChildWorkflowOptions": {
+				"WorkflowIDPath": "$.id",
+				"ExecutionStartToCloseTimeoutSeconds": 60,
+				"ParentClosePolicy": "REQUEST_CANCEL"
+			},
+			"ResultPath": "$.child",
+			"Next": "Example2"
+		},
+		"Example2": {
+			"Type": "Task",
+			"Resource": "cadence:workflow:TestChildWorkflow",
+			"ResultPath": "$.started",
+			"Next": "Example3"
+		},
+		"Example3": {
+			"Type": "Task",
+			"Resource": "cadence:workflow:TestChildFailWorkflow.sync",
+			"Catch": [
+				{
+					"ErrorEquals": ["States.TaskFailed"],
+					"ResultPath": "$.error",
+					"Next": "Example4"
+				}
+			],
+			"End": true
+		},
+		"Example4": {
+			"Type": "Succeed"
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Child_Workflow() {
+	for name, raw := range map[string][]byte{
+		"TestChildWorkflow":     childMachine,
+		"TestChildFailWorkflow": childFailMachine,
+		"TestParentWorkflow":    parentMachine,
+	} {
+		sm, err := FromJSON(raw)
+		if err != nil {
+			s.NoError(err)
+			return
+		}
+		RegisterWorkflow(name, *sm)
+	}
+
+	s.env.ExecuteWorkflow("TestParentWorkflow", map[string]interface{}{"id": "child-id"})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+
+	s.Equal(map[string]interface{}{"id": "child-id", "from": "child"}, result["child"])
+
+	if s.Contains(result, "started") {
+		started := result["started"].(map[string]interface{})
+		s.NotEmpty(started["WorkflowId"])
+		s.NotEmpty(started["RunId"])
+	}
+
+	s.Equal(map[string]interface{}{
+		"Error": StatesTaskFailed,
+		"Cause": "ChildError: the child failed",
+	}, result["error"])
+}
+
+func TestChildWorkflowValidate(t *testing.T) {
+	tests := []string{
+		`{"Type": "Task", "Resource": "cadence:workflow:", "End": true}`,
+		`{"Type": "Task", "Resource": "example:activity:Example", "ChildWorkflowOptions": {}, "End": true}`,
+		`{"Type": "Task", "Resource": "cadence:workflow:Example", "ChildWorkflowOptions": {"ParentClosePolicy": "IGNORE"}, "End": true}`,
+		`{"Type": "Task", "Resource": "cadence:workflow:Example", "ChildWorkflowOptions": {"WorkflowID": "id", "WorkflowIDPath": "$.id"}, "End": true}`,
+	}
+
+	for _, test := range tests {
+		_, err := FromJSON([]byte(`{"StartAt": "Example1", "States": {"Example1": ` + test + `}}`))
+		assert.Error(t, err, test)
+	}
+
+	name, sync := parseChildWorkflowResource("cadence:workflow:example:workflow:Example.sync")
+	assert.Equal(t, "example:workflow:Example", name)
+	assert.True(t, sync)
+}
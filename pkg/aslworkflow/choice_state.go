@@ -81,15 +81,46 @@ type ChoiceRule struct {
 	TimestampLessThanEquals    *time.Time `json:",omitempty"`
 	TimestampGreaterThanEquals *time.Time `json:",omitempty"`
 
+	StringMatches *string `json:",omitempty"` // * is a wildcard, \* and \\ are escapes
+
+	IsPresent   *bool `json:",omitempty"`
+	IsNull      *bool `json:",omitempty"`
+	IsString    *bool `json:",omitempty"`
+	IsNumeric   *bool `json:",omitempty"`
+	IsBoolean   *bool `json:",omitempty"`
+	IsTimestamp *bool `json:",omitempty"`
+
+	// Path variants compare the Variable to another value in the input
+
+	StringEqualsPath            *jsonpath.Path `json:",omitempty"`
+	StringLessThanPath          *jsonpath.Path `json:",omitempty"`
+	StringGreaterThanPath       *jsonpath.Path `json:",omitempty"`
+	StringLessThanEqualsPath    *jsonpath.Path `json:",omitempty"`
+	StringGreaterThanEqualsPath *jsonpath.Path `json:",omitempty"`
+
+	NumericEqualsPath            *jsonpath.Path `json:",omitempty"`
+	NumericLessThanPath          *jsonpath.Path `json:",omitempty"`
+	NumericGreaterThanPath       *jsonpath.Path `json:",omitempty"`
+	NumericLessThanEqualsPath    *jsonpath.Path `json:",omitempty"`
+	NumericGreaterThanEqualsPath *jsonpath.Path `json:",omitempty"`
+
+	BooleanEqualsPath *jsonpath.Path `json:",omitempty"`
+
+	TimestampEqualsPath            *jsonpath.Path `json:",omitempty"`
+	TimestampLessThanPath          *jsonpath.Path `json:",omitempty"`
+	TimestampGreaterThanPath       *jsonpath.Path `json:",omitempty"`
+	TimestampLessThanEqualsPath    *jsonpath.Path `json:",omitempty"`
+	TimestampGreaterThanEqualsPath *jsonpath.Path `json:",omitempty"`
+
 	And []*ChoiceRule `json:",omitempty"`
 	Or  []*ChoiceRule `json:",omitempty"`
 	Not *ChoiceRule   `json:",omitempty"`
 }
 
 func (s *ChoiceState) process(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
-	next := chooseNextState(input, s.Default, s.Choices)
+	next := chooseNextState(choiceInput{input: input, context: contextObject(ctx)}, s.Default, s.Choices)
 	if next == nil {
-		return nil, nil, fmt.Errorf("choice state error: no choice found")
+		return nil, nil, newStatesError(StatesNoChoiceMatched, "no choice rule matched and there is no Default")
 	}
 	return input, next, nil
 }
@@ -105,7 +136,21 @@ func (s *ChoiceState) Execute(ctx workflow.Context, input interface{}) (output i
 	)(ctx, input)
 }
 
-func chooseNextState(input interface{}, defaultChoice *string, choices []*Choice) *string {
+// choiceInput is what choice rules are evaluated against, paths starting with $$ read the context object
+type choiceInput struct {
+	input   interface{}
+	context interface{}
+}
+
+// at returns the value the path should be resolved against
+func (c choiceInput) at(path *jsonpath.Path) interface{} {
+	if path.IsContext() {
+		return c.context
+	}
+	return c.input
+}
+
+func chooseNextState(input choiceInput, defaultChoice *string, choices []*Choice) *string {
 	for _, choice := range choices {
 		if choiceRulePositive(input, &choice.ChoiceRule) {
 			return choice.Next
@@ -114,7 +159,7 @@ func chooseNextState(input interface{}, defaultChoice *string, choices []*Choice
 	return defaultChoice
 }
 
-func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
+func choiceRulePositive(input choiceInput, cr *ChoiceRule) bool {
 	if cr.And != nil {
 		for _, a := range cr.And {
 			// if any choices have false then return false
@@ -140,7 +185,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.StringEquals != nil {
-		vstr, err := cr.Variable.GetString(input)
+		vstr, err := cr.Variable.GetString(input.at(cr.Variable))
 		if err != nil {
 			return false // either not found or bad type
 		}
@@ -148,7 +193,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.StringLessThan != nil {
-		vstr, err := cr.Variable.GetString(input)
+		vstr, err := cr.Variable.GetString(input.at(cr.Variable))
 		if err != nil {
 			return false // either not found or bad type
 		}
@@ -156,7 +201,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.StringGreaterThan != nil {
-		vstr, err := cr.Variable.GetString(input)
+		vstr, err := cr.Variable.GetString(input.at(cr.Variable))
 		if err != nil {
 			return false // either not found or bad type
 		}
@@ -164,7 +209,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.StringLessThanEquals != nil {
-		vstr, err := cr.Variable.GetString(input)
+		vstr, err := cr.Variable.GetString(input.at(cr.Variable))
 		if err != nil {
 			return false // either not found or bad type
 		}
@@ -172,7 +217,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.StringGreaterThanEquals != nil {
-		vstr, err := cr.Variable.GetString(input)
+		vstr, err := cr.Variable.GetString(input.at(cr.Variable))
 		if err != nil {
 			return false // either not found or bad type
 		}
@@ -181,7 +226,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 
 	// NUMBERs
 	if cr.NumericEquals != nil {
-		vnum, err := cr.Variable.GetNumber(input)
+		vnum, err := cr.Variable.GetNumber(input.at(cr.Variable))
 		if err != nil {
 			return false
 		}
@@ -189,7 +234,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.NumericLessThan != nil {
-		vnum, err := cr.Variable.GetNumber(input)
+		vnum, err := cr.Variable.GetNumber(input.at(cr.Variable))
 		if err != nil {
 			return false
 		}
@@ -197,7 +242,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.NumericGreaterThan != nil {
-		vnum, err := cr.Variable.GetNumber(input)
+		vnum, err := cr.Variable.GetNumber(input.at(cr.Variable))
 		if err != nil {
 			return false
 		}
@@ -205,7 +250,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.NumericLessThanEquals != nil {
-		vnum, err := cr.Variable.GetNumber(input)
+		vnum, err := cr.Variable.GetNumber(input.at(cr.Variable))
 		if err != nil {
 			return false
 		}
@@ -213,7 +258,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.NumericGreaterThanEquals != nil {
-		vnum, err := cr.Variable.GetNumber(input)
+		vnum, err := cr.Variable.GetNumber(input.at(cr.Variable))
 		if err != nil {
 			return false
 		}
@@ -221,7 +266,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.BooleanEquals != nil {
-		vbool, err := cr.Variable.GetBool(input)
+		vbool, err := cr.Variable.GetBool(input.at(cr.Variable))
 		if err != nil {
 			return false
 		}
@@ -229,7 +274,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.TimestampEquals != nil {
-		vtime, err := cr.Variable.GetTime(input)
+		vtime, err := cr.Variable.GetTime(input.at(cr.Variable))
 		if err != nil {
 			return false
 		}
@@ -237,7 +282,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.TimestampLessThan != nil {
-		vtime, err := cr.Variable.GetTime(input)
+		vtime, err := cr.Variable.GetTime(input.at(cr.Variable))
 		if err != nil {
 			return false
 		}
@@ -245,7 +290,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.TimestampGreaterThan != nil {
-		vtime, err := cr.Variable.GetTime(input)
+		vtime, err := cr.Variable.GetTime(input.at(cr.Variable))
 		if err != nil {
 			return false
 		}
@@ -253,7 +298,7 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.TimestampLessThanEquals != nil {
-		vtime, err := cr.Variable.GetTime(input)
+		vtime, err := cr.Variable.GetTime(input.at(cr.Variable))
 		if err != nil {
 			return false
 		}
@@ -261,16 +306,242 @@ func choiceRulePositive(input interface{}, cr *ChoiceRule) bool {
 	}
 
 	if cr.TimestampGreaterThanEquals != nil {
-		vtime, err := cr.Variable.GetTime(input)
+		vtime, err := cr.Variable.GetTime(input.at(cr.Variable))
 		if err != nil {
 			return false
 		}
 		return *vtime == *cr.TimestampGreaterThanEquals || vtime.After(*cr.TimestampGreaterThanEquals)
 	}
 
+	if cr.StringMatches != nil {
+		vstr, err := cr.Variable.GetString(input.at(cr.Variable))
+		if err != nil {
+			return false
+		}
+		return stringMatches(*vstr, *cr.StringMatches)
+	}
+
+	// TYPE CHECKS
+	if cr.IsPresent != nil {
+		_, err := cr.Variable.Get(input.at(cr.Variable))
+		return (err == nil) == *cr.IsPresent
+	}
+
+	if cr.IsNull != nil {
+		value, err := cr.Variable.Get(input.at(cr.Variable))
+		if err != nil {
+			return false // not present is not null
+		}
+		return (value == nil) == *cr.IsNull
+	}
+
+	if cr.IsString != nil {
+		value, err := cr.Variable.Get(input.at(cr.Variable))
+		if err != nil {
+			return false
+		}
+		_, ok := value.(string)
+		return ok == *cr.IsString
+	}
+
+	if cr.IsNumeric != nil {
+		_, err := cr.Variable.Get(input.at(cr.Variable))
+		if err != nil {
+			return false
+		}
+		_, err = cr.Variable.GetNumber(input.at(cr.Variable))
+		return (err == nil) == *cr.IsNumeric
+	}
+
+	if cr.IsBoolean != nil {
+		value, err := cr.Variable.Get(input.at(cr.Variable))
+		if err != nil {
+			return false
+		}
+		_, ok := value.(bool)
+		return ok == *cr.IsBoolean
+	}
+
+	if cr.IsTimestamp != nil {
+		_, err := cr.Variable.Get(input.at(cr.Variable))
+		if err != nil {
+			return false
+		}
+		_, err = cr.Variable.GetTime(input.at(cr.Variable))
+		return (err == nil) == *cr.IsTimestamp
+	}
+
+	// PATH COMPARISONS
+	if cr.StringEqualsPath != nil {
+		vstr, other, ok := stringOperands(input, cr.Variable, cr.StringEqualsPath)
+		return ok && vstr == other
+	}
+
+	if cr.StringLessThanPath != nil {
+		vstr, other, ok := stringOperands(input, cr.Variable, cr.StringLessThanPath)
+		return ok && vstr < other
+	}
+
+	if cr.StringGreaterThanPath != nil {
+		vstr, other, ok := stringOperands(input, cr.Variable, cr.StringGreaterThanPath)
+		return ok && vstr > other
+	}
+
+	if cr.StringLessThanEqualsPath != nil {
+		vstr, other, ok := stringOperands(input, cr.Variable, cr.StringLessThanEqualsPath)
+		return ok && vstr <= other
+	}
+
+	if cr.StringGreaterThanEqualsPath != nil {
+		vstr, other, ok := stringOperands(input, cr.Variable, cr.StringGreaterThanEqualsPath)
+		return ok && vstr >= other
+	}
+
+	if cr.NumericEqualsPath != nil {
+		vnum, other, ok := numericOperands(input, cr.Variable, cr.NumericEqualsPath)
+		return ok && vnum == other
+	}
+
+	if cr.NumericLessThanPath != nil {
+		vnum, other, ok := numericOperands(input, cr.Variable, cr.NumericLessThanPath)
+		return ok && vnum < other
+	}
+
+	if cr.NumericGreaterThanPath != nil {
+		vnum, other, ok := numericOperands(input, cr.Variable, cr.NumericGreaterThanPath)
+		return ok && vnum > other
+	}
+
+	if cr.NumericLessThanEqualsPath != nil {
+		vnum, other, ok := numericOperands(input, cr.Variable, cr.NumericLessThanEqualsPath)
+		return ok && vnum <= other
+	}
+
+	if cr.NumericGreaterThanEqualsPath != nil {
+		vnum, other, ok := numericOperands(input, cr.Variable, cr.NumericGreaterThanEqualsPath)
+		return ok && vnum >= other
+	}
+
+	if cr.BooleanEqualsPath != nil {
+		vbool, err := cr.Variable.GetBool(input.at(cr.Variable))
+		if err != nil {
+			return false
+		}
+		other, err := cr.BooleanEqualsPath.GetBool(input.at(cr.BooleanEqualsPath))
+		if err != nil {
+			return false
+		}
+		return *vbool == *other
+	}
+
+	if cr.TimestampEqualsPath != nil {
+		vtime, other, ok := timestampOperands(input, cr.Variable, cr.TimestampEqualsPath)
+		return ok && vtime.Equal(other)
+	}
+
+	if cr.TimestampLessThanPath != nil {
+		vtime, other, ok := timestampOperands(input, cr.Variable, cr.TimestampLessThanPath)
+		return ok && vtime.Before(other)
+	}
+
+	if cr.TimestampGreaterThanPath != nil {
+		vtime, other, ok := timestampOperands(input, cr.Variable, cr.TimestampGreaterThanPath)
+		return ok && vtime.After(other)
+	}
+
+	if cr.TimestampLessThanEqualsPath != nil {
+		vtime, other, ok := timestampOperands(input, cr.Variable, cr.TimestampLessThanEqualsPath)
+		return ok && !vtime.After(other)
+	}
+
+	if cr.TimestampGreaterThanEqualsPath != nil {
+		vtime, other, ok := timestampOperands(input, cr.Variable, cr.TimestampGreaterThanEqualsPath)
+		return ok && !vtime.Before(other)
+	}
+
 	return false
 }
 
+// stringOperands returns the strings at both paths, ok is false if either is missing or not a string
+func stringOperands(input choiceInput, variable *jsonpath.Path, path *jsonpath.Path) (string, string, bool) {
+	vstr, err := variable.GetString(input.at(variable))
+	if err != nil {
+		return "", "", false
+	}
+	other, err := path.GetString(input.at(path))
+	if err != nil {
+		return "", "", false
+	}
+	return *vstr, *other, true
+}
+
+// numericOperands returns the numbers at both paths, ok is false if either is missing or not a number
+func numericOperands(input choiceInput, variable *jsonpath.Path, path *jsonpath.Path) (float64, float64, bool) {
+	vnum, err := variable.GetNumber(input.at(variable))
+	if err != nil {
+		return 0, 0, false
+	}
+	other, err := path.GetNumber(input.at(path))
+	if err != nil {
+		return 0, 0, false
+	}
+	return *vnum, *other, true
+}
+
+// timestampOperands returns the times at both paths, ok is false if either is missing or not a timestamp
+func timestampOperands(input choiceInput, variable *jsonpath.Path, path *jsonpath.Path) (time.Time, time.Time, bool) {
+	vtime, err := variable.GetTime(input.at(variable))
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	other, err := path.GetTime(input.at(path))
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return *vtime, *other, true
+}
+
+// stringMatches returns true if value matches the pattern, where * matches any number of characters.
+// \* matches a literal * and \\ a literal \.
+func stringMatches(value string, pattern string) bool {
+	// Unescape the pattern first, wildcard marks the positions of unescaped *
+	var literal []byte
+	var wildcard []bool
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c == '\\' && i+1 < len(pattern) {
+			i++
+			literal, wildcard = append(literal, pattern[i]), append(wildcard, false)
+			continue
+		}
+		literal, wildcard = append(literal, c), append(wildcard, c == '*')
+	}
+
+	// Match left to right, on a mismatch let the last * take one more character and retry from there
+	v, p := 0, 0
+	star, starV := -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(literal) && wildcard[p]:
+			star, starV = p, v
+			p++
+		case p < len(literal) && literal[p] == value[v]:
+			v++
+			p++
+		case star >= 0:
+			starV++
+			v, p = starV, star+1
+		default:
+			return false
+		}
+	}
+
+	for p < len(literal) && wildcard[p] {
+		p++
+	}
+	return p == len(literal)
+}
+
 // VALIDATION LOGIC
 
 func (s *ChoiceState) Validate() error {
@@ -358,6 +629,29 @@ func validateChoiceRule(c *ChoiceRule) error {
 		c.TimestampGreaterThan != nil,
 		c.TimestampLessThanEquals != nil,
 		c.TimestampGreaterThanEquals != nil,
+		c.StringMatches != nil,
+		c.IsPresent != nil,
+		c.IsNull != nil,
+		c.IsString != nil,
+		c.IsNumeric != nil,
+		c.IsBoolean != nil,
+		c.IsTimestamp != nil,
+		c.StringEqualsPath != nil,
+		c.StringLessThanPath != nil,
+		c.StringGreaterThanPath != nil,
+		c.StringLessThanEqualsPath != nil,
+		c.StringGreaterThanEqualsPath != nil,
+		c.NumericEqualsPath != nil,
+		c.NumericLessThanPath != nil,
+		c.NumericGreaterThanPath != nil,
+		c.NumericLessThanEqualsPath != nil,
+		c.NumericGreaterThanEqualsPath != nil,
+		c.BooleanEqualsPath != nil,
+		c.TimestampEqualsPath != nil,
+		c.TimestampLessThanPath != nil,
+		c.TimestampGreaterThanPath != nil,
+		c.TimestampLessThanEqualsPath != nil,
+		c.TimestampGreaterThanEqualsPath != nil,
 	}
 
 	count := 0
@@ -384,6 +678,35 @@ func validateChoiceRule(c *ChoiceRule) error {
 		}
 	}
 
+	if err := isReferencePathValid("Variable", c.Variable); err != nil {
+		return err
+	}
+
+	comparisonPaths := map[string]*jsonpath.Path{
+		"StringEqualsPath":               c.StringEqualsPath,
+		"StringLessThanPath":             c.StringLessThanPath,
+		"StringGreaterThanPath":          c.StringGreaterThanPath,
+		"StringLessThanEqualsPath":       c.StringLessThanEqualsPath,
+		"StringGreaterThanEqualsPath":    c.StringGreaterThanEqualsPath,
+		"NumericEqualsPath":              c.NumericEqualsPath,
+		"NumericLessThanPath":            c.NumericLessThanPath,
+		"NumericGreaterThanPath":         c.NumericGreaterThanPath,
+		"NumericLessThanEqualsPath":      c.NumericLessThanEqualsPath,
+		"NumericGreaterThanEqualsPath":   c.NumericGreaterThanEqualsPath,
+		"BooleanEqualsPath":              c.BooleanEqualsPath,
+		"TimestampEqualsPath":            c.TimestampEqualsPath,
+		"TimestampLessThanPath":          c.TimestampLessThanPath,
+		"TimestampGreaterThanPath":       c.TimestampGreaterThanPath,
+		"TimestampLessThanEqualsPath":    c.TimestampLessThanEqualsPath,
+		"TimestampGreaterThanEqualsPath": c.TimestampGreaterThanEqualsPath,
+	}
+
+	for name, path := range comparisonPaths {
+		if err := isReferencePathValid(name, path); err != nil {
+			return err
+		}
+	}
+
 	if c.And != nil && len(c.And) == 0 {
 		return fmt.Errorf("And Must have elements")
 	}
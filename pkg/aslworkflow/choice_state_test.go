@@ -0,0 +1,118 @@
+package aslworkflow
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChoiceRuleExtendedComparators(t *testing.T) {
+	var input interface{}
+	err := json.Unmarshal([]byte(`{
+		"name": "report-2020.csv",
+		"pattern": "report-*.csv",
+		"null": null,
+		"count": 5,
+		"limit": 10,
+		"enabled": true,
+		"expected": true,
+		"created": "2020-01-01T00:00:00Z",
+		"updated": "2020-06-01T00:00:00Z",
+		"star": "a*b"
+	}`), &input)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		rule     string
+		expected bool
+	}{
+		{`{"Variable": "$.name", "IsPresent": true}`, true},
+		{`{"Variable": "$.missing", "IsPresent": true}`, false},
+		{`{"Variable": "$.missing", "IsPresent": false}`, true},
+		{`{"Variable": "$.null", "IsNull": true}`, true},
+		{`{"Variable": "$.name", "IsNull": true}`, false},
+		{`{"Variable": "$.missing", "IsNull": true}`, false},
+		{`{"Variable": "$.name", "IsString": true}`, true},
+		{`{"Variable": "$.count", "IsString": false}`, true},
+		{`{"Variable": "$.count", "IsNumeric": true}`, true},
+		{`{"Variable": "$.name", "IsNumeric": true}`, false},
+		{`{"Variable": "$.enabled", "IsBoolean": true}`, true},
+		{`{"Variable": "$.created", "IsTimestamp": true}`, true},
+		{`{"Variable": "$.name", "IsTimestamp": true}`, false},
+		{`{"Variable": "$.name", "StringMatches": "report-*.csv"}`, true},
+		{`{"Variable": "$.name", "StringMatches": "*.json"}`, false},
+		{`{"Variable": "$.name", "StringMatches": "*"}`, true},
+		{`{"Variable": "$.star", "StringMatches": "a\\*b"}`, true},
+		{`{"Variable": "$.name", "StringMatches": "report-\\*.csv"}`, false},
+		{`{"Variable": "$.pattern", "StringEqualsPath": "$.pattern"}`, true},
+		{`{"Variable": "$.name", "StringEqualsPath": "$.pattern"}`, false},
+		{`{"Variable": "$.name", "StringLessThanPath": "$.star"}`, false},
+		{`{"Variable": "$.star", "StringLessThanEqualsPath": "$.name"}`, true},
+		{`{"Variable": "$.count", "NumericLessThanPath": "$.limit"}`, true},
+		{`{"Variable": "$.count", "NumericGreaterThanPath": "$.limit"}`, false},
+		{`{"Variable": "$.count", "NumericEqualsPath": "$.count"}`, true},
+		{`{"Variable": "$.count", "NumericGreaterThanEqualsPath": "$.missing"}`, false},
+		{`{"Variable": "$.enabled", "BooleanEqualsPath": "$.expected"}`, true},
+		{`{"Variable": "$.created", "TimestampLessThanPath": "$.updated"}`, true},
+		{`{"Variable": "$.created", "TimestampGreaterThanEqualsPath": "$.updated"}`, false},
+		{`{"Variable": "$.created", "TimestampEqualsPath": "$.created"}`, true},
+		{`{"Not": {"Variable": "$.missing", "IsPresent": true}}`, true},
+	}
+
+	for _, test := range tests {
+		var rule ChoiceRule
+		if !assert.NoError(t, json.Unmarshal([]byte(test.rule), &rule), test.rule) {
+			continue
+		}
+
+		assert.NoError(t, validateChoiceRule(&rule), test.rule)
+		assert.Equal(t, test.expected, choiceRulePositive(choiceInput{input: input}, &rule), test.rule)
+	}
+}
+
+func TestChoiceRuleValidate(t *testing.T) {
+	for _, invalid := range []string{
+		`{"Variable": "$.a", "IsPresent": true, "IsNull": true}`,
+		`{"Variable": "$.a", "StringEquals": "a", "StringEqualsPath": "$.b"}`,
+		`{"Variable": "$.a", "NumericEqualsPath": "$.b[*]"}`,
+		`{"IsPresent": true}`,
+	} {
+		var rule ChoiceRule
+		if !assert.NoError(t, json.Unmarshal([]byte(invalid), &rule), invalid) {
+			continue
+		}
+
+		assert.Error(t, validateChoiceRule(&rule), invalid)
+	}
+}
+
+func TestStringMatches(t *testing.T) {
+	tests := []struct {
+		value    string
+		pattern  string
+		expected bool
+	}{
+		{"", "", true},
+		{"", "*", true},
+		{"a", "", false},
+		{"abc", "a*c", true},
+		{"abc", "a*b", false},
+		{"abcbc", "*bc", true},
+		{"ab", "a**b", true},
+		{"a*b", `a\*b`, true},
+		{"axb", `a\*b`, false},
+		{`a\b`, `a\\b`, true},
+		{`a\`, `a\`, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, stringMatches(test.value, test.pattern), "%q %q", test.value, test.pattern)
+	}
+
+	// Backtracking through every star would never finish on this
+	value := strings.Repeat("a", 10000)
+	assert.False(t, stringMatches(value, strings.Repeat("a*", 50)+"b"))
+	assert.True(t, stringMatches(value+"b", strings.Repeat("*a", 50)+"*b"))
+}
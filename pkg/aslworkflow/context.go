@@ -0,0 +1,147 @@
+package aslworkflow
+
+import (
+	"time"
+
+	"github.com/checkr/states-language-cadence/pkg/jsonpath"
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// The context object holds information about the current execution and state, paths starting with $$
+// are resolved against it instead of the state input. They can be used in Parameters, ItemSelector,
+// InputPath, OutputPath, Choice rules and the other paths states read from, e.g. ItemsPath or
+// SecondsPath, but not in ResultPath.
+//
+//	"Parameters": {
+//		"idempotencyKey.$": "$$.Execution.Id",
+//		"attempt.$": "$$.State.RetryCount"
+//	}
+
+type contextKey string
+
+const executionContextKey contextKey = "aslExecutionContext"
+
+// executionContext is the interpreter state used to build the context object
+type executionContext struct {
+	input            interface{}
+	startTime        time.Time
+	stateName        string
+	stateEnteredTime time.Time
+	retryCount       int
+	taskToken        string
+	mapItem          *mapItemContext
+}
+
+type mapItemContext struct {
+	index int
+	value interface{}
+}
+
+func getExecutionContext(ctx workflow.Context) executionContext {
+	if ec, ok := ctx.Value(executionContextKey).(executionContext); ok {
+		return ec
+	}
+	return executionContext{}
+}
+
+func withExecutionContext(ctx workflow.Context, ec executionContext) workflow.Context {
+	return workflow.WithValue(ctx, executionContextKey, ec)
+}
+
+// withExecution starts a new context object for an execution of a state machine
+func withExecution(ctx workflow.Context, input interface{}) workflow.Context {
+	return withExecutionContext(ctx, executionContext{
+		input:     input,
+		startTime: workflow.Now(ctx),
+	})
+}
+
+// withState records the state being entered, resetting any per state values
+func withState(ctx workflow.Context, name string) workflow.Context {
+	ec := getExecutionContext(ctx)
+	ec.stateName = name
+	ec.stateEnteredTime = workflow.Now(ctx)
+	ec.retryCount = 0
+	ec.taskToken = ""
+	ec.mapItem = nil
+	return withExecutionContext(ctx, ec)
+}
+
+func withRetryCount(ctx workflow.Context, retryCount int) workflow.Context {
+	ec := getExecutionContext(ctx)
+	ec.retryCount = retryCount
+	return withExecutionContext(ctx, ec)
+}
+
+func withTaskToken(ctx workflow.Context, token string) workflow.Context {
+	ec := getExecutionContext(ctx)
+	ec.taskToken = token
+	return withExecutionContext(ctx, ec)
+}
+
+func withMapItem(ctx workflow.Context, index int, value interface{}) workflow.Context {
+	ec := getExecutionContext(ctx)
+	ec.mapItem = &mapItemContext{index: index, value: value}
+	return withExecutionContext(ctx, ec)
+}
+
+// contextObject returns the context object as JSON compatible values
+func contextObject(ctx workflow.Context) map[string]interface{} {
+	ec := getExecutionContext(ctx)
+	info := workflow.GetInfo(ctx)
+
+	co := map[string]interface{}{
+		"Execution": map[string]interface{}{
+			"Id":        info.WorkflowExecution.ID,
+			"Name":      info.WorkflowExecution.ID,
+			"RunId":     info.WorkflowExecution.RunID,
+			"Input":     ec.input,
+			"StartTime": formatContextTime(ec.startTime),
+		},
+		"StateMachine": map[string]interface{}{
+			"Id":   info.WorkflowType.Name,
+			"Name": info.WorkflowType.Name,
+		},
+		"State": map[string]interface{}{
+			"Name":        ec.stateName,
+			"EnteredTime": formatContextTime(ec.stateEnteredTime),
+			"RetryCount":  float64(ec.retryCount),
+		},
+	}
+
+	if ec.taskToken != "" {
+		co["Task"] = map[string]interface{}{
+			"Token": ec.taskToken,
+		}
+	}
+
+	if ec.mapItem != nil {
+		co["Map"] = map[string]interface{}{
+			"Item": map[string]interface{}{
+				"Index": float64(ec.mapItem.index),
+				"Value": ec.mapItem.value,
+			},
+		}
+	}
+
+	return co
+}
+
+func formatContextTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// getPath resolves a path against the input, or against the context object if it starts with $$
+func getPath(ctx workflow.Context, path *jsonpath.Path, input interface{}) (interface{}, error) {
+	return path.Get(pathSource(ctx, path, input))
+}
+
+// pathSource returns what a path is resolved against, the context object if it starts with $$ otherwise
+// the input, for the typed getters such as GetNumber
+func pathSource(ctx workflow.Context, path *jsonpath.Path, input interface{}) interface{} {
+	if path.IsContext() {
+		return contextObject(ctx)
+	}
+	return input
+}
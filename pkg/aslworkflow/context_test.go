@@ -0,0 +1,236 @@
+package aslworkflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/cadence/workflow"
+)
+
+var contextMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "arn:aws:resource:example",
+			"Parameters": {
+				"executionId.$": "$$.Execution.Id",
+				"executionInput.$": "$$.Execution.Input",
+				"stateMachine.$": "$$.StateMachine.Name",
+				"stateName.$": "$$.State.Name",
+				"retryCount.$": "$$.State.RetryCount",
+				"key.$": "States.Format('{}-{}', $$.Execution.Id, $.id)"
+			},
+			"Retry": [
+				{
+					"ErrorEquals": ["States.ALL"]
+				}
+			],
+			"Next": "Example2"
+		},
+		"Example2": {
+			"Type": "Map",
+			"ItemsPath": "$.items",
+			"ItemSelector": {
+				"index.$": "$$.Map.Item.Index",
+				"value.$": "$$.Map.Item.Value",
+				"executionId.$": "$$.Execution.Id"
+			},
+			"ItemProcessor": {
+				"StartAt": "Item",
+				"States": {
+					"Item": {
+						"Type": "Pass",
+						"End": true
+					}
+				}
+			},
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Context_Object() {
+	sm, err := FromJSON(contextMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	var inputs []map[string]interface{}
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		inputs = append(inputs, input.(map[string]interface{}))
+		if len(inputs) == 1 {
+			return nil, errors.New("task error")
+		}
+		return map[string]interface{}{"items": []interface{}{"a", "b"}}, nil
+	}
+	RegisterHandler(handler)
+
+	exampleInput := map[string]interface{}{"id": "example"}
+
+	RegisterWorkflow("TestContextWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestContextWorkflow", exampleInput)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	executionID := "default-test-workflow-id"
+
+	s.Len(inputs, 2)
+	s.Equal(executionID, inputs[0]["executionId"])
+	s.Equal(exampleInput, inputs[0]["executionInput"])
+	s.Equal("TestContextWorkflow", inputs[0]["stateMachine"])
+	s.Equal("Example1", inputs[0]["stateName"])
+	s.Equal(float64(0), inputs[0]["retryCount"])
+	s.Equal(float64(1), inputs[1]["retryCount"])
+	s.Equal(executionID+"-example", inputs[1]["key"])
+
+	var result []map[string]interface{}
+	err = s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+
+	s.Equal([]map[string]interface{}{
+		{"index": float64(0), "value": "a", "executionId": executionID},
+		{"index": float64(1), "value": "b", "executionId": executionID},
+	}, result)
+}
+
+var contextPathsMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Pass",
+			"Result": {"mode": "state"},
+			"Next": "Example2"
+		},
+		"Example2": {
+			"Type": "Choice",
+			"Choices": [
+				{"Variable": "$$.Execution.Input.mode", "StringEquals": "execution", "Next": "Example3"}
+			]
+		},
+		"Example3": {
+			"Type": "Pass",
+			"InputPath": "$$.Execution.Input",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Context_Paths() {
+	sm, err := FromJSON(contextPathsMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestContextPathsWorkflow", *sm)
+	s.env.ExecuteWorkflow("TestContextPathsWorkflow", map[string]interface{}{"mode": "execution"})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{"mode": "execution"}, result)
+}
+
+var contextStatePathsMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Pass",
+			"Result": {"replaced": true},
+			"Next": "Example2"
+		},
+		"Example2": {
+			"Type": "Map",
+			"ItemsPath": "$$.Execution.Input.items",
+			"ItemProcessor": {
+				"StartAt": "Item",
+				"States": {
+					"Item": {
+						"Type": "Pass",
+						"End": true
+					}
+				}
+			},
+			"ResultPath": "$.items",
+			"Next": "Example3"
+		},
+		"Example3": {
+			"Type": "Wait",
+			"SecondsPath": "$$.Execution.Input.wait",
+			"Next": "Example4"
+		},
+		"Example4": {
+			"Type": "Task",
+			"Resource": "arn:aws:resource:example",
+			"TimeoutSecondsPath": "$$.Execution.Input.timeout",
+			"HeartbeatSecondsPath": "$$.Execution.Input.heartbeat",
+			"ResultPath": "$.task",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Context_State_Paths() {
+	sm, err := FromJSON(contextStatePathsMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	var options []workflow.ActivityOptions
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		options = append(options, getActivityOptions(ctx))
+		return "done", nil
+	}
+	RegisterHandler(handler)
+	defer DeregisterHandler()
+
+	RegisterWorkflow("TestContextStatePathsWorkflow", *sm)
+
+	startTime := s.env.Now()
+	s.env.ExecuteWorkflow("TestContextStatePathsWorkflow", map[string]interface{}{
+		"items":     []interface{}{map[string]interface{}{"id": "a"}, map[string]interface{}{"id": "b"}},
+		"wait":      60,
+		"timeout":   30,
+		"heartbeat": 10,
+	})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(time.Minute, s.env.Now().Sub(startTime))
+
+	if s.Len(options, 1) {
+		s.Equal(30*time.Second, options[0].StartToCloseTimeout)
+		s.Equal(10*time.Second, options[0].HeartbeatTimeout)
+	}
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{
+		"replaced": true,
+		"items":    []interface{}{map[string]interface{}{"id": "a"}, map[string]interface{}{"id": "b"}},
+		"task":     "done",
+	}, result)
+}
+
+func TestContextResultPathValidate(t *testing.T) {
+	_, err := FromJSON([]byte(`{
+		"StartAt": "Example1",
+		"States": {"Example1": {"Type": "Pass", "ResultPath": "$$.State.Name", "End": true}}
+	}`))
+	assert.EqualError(t, err, "invalid state machine:\nPassState(Example1) Error: ResultPath must not refer to the context object")
+}
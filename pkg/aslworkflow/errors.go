@@ -0,0 +1,139 @@
+package aslworkflow
+
+import (
+	"errors"
+
+	"github.com/coinbase/step/utils/to"
+	"go.uber.org/cadence"
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// Errors are matched against ErrorEquals by name. Activities and handlers raise named errors with
+// NewError, which survives being returned from an activity or a workflow, and inspect errors with
+// ErrorName, ErrorCause and ErrorDetails.
+//
+//	return nil, aslworkflow.NewError("Order.OutOfStock", "item 42 is out of stock", map[string]interface{}{"item": 42})
+//
+//	"Catch": [{"ErrorEquals": ["Order.OutOfStock"], "ResultPath": "$.error", "Next": "Backorder"}]
+//
+// Errors raised by the interpreter are named errors with one of the predefined States.* names. Errors
+// from Cadence are classified as:
+//
+//	*cadence.CustomError    its Reason()
+//	*workflow.TimeoutError  States.Timeout
+//	*workflow.GenericError  States.TaskFailed (returned by activities and child workflows)
+//	*workflow.PanicError    States.TaskFailed
+//
+// Anything else is named by its Go type. Cancellations, e.g. of a branch after another branch failed,
+// are not failures of the state so they are never retried or caught.
+
+// NewError returns an error named name, the name is matched by ErrorEquals and is the Error of the error
+// output, the cause is its Cause. Details are optional and must be serializable to JSON.
+func NewError(name string, cause string, details interface{}) error {
+	output := errorOutput(&name, &cause)
+	if details != nil {
+		output["Details"] = details
+	}
+	return cadence.NewCustomError(name, output)
+}
+
+// ErrorName returns the name of the error matched against ErrorEquals
+func ErrorName(err error) string {
+	if name, ok := classifyError(err); ok {
+		return name
+	}
+	return to.ErrorType(err)
+}
+
+// classifyError returns the name of the first classified error in the chain, outermost first, so a
+// States.BranchFailed wrapping a States.TaskFailed is a States.BranchFailed
+func classifyError(err error) (string, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch e := err.(type) {
+		case *cadence.CustomError:
+			return e.Reason(), true
+		case *workflow.TimeoutError:
+			return StatesTimeout, true
+		case *workflow.GenericError, *workflow.PanicError:
+			return StatesTaskFailed, true
+		}
+	}
+	return "", false
+}
+
+// ErrorCause returns the Cause of the error output. Errors created by NewError only return their name from
+// Error() so the cause is read from their details.
+func ErrorCause(err error) string {
+	if output, ok := errorOutputDetails(err); ok {
+		if cause, ok := output["Cause"].(string); ok {
+			return cause
+		}
+	}
+	return err.Error()
+}
+
+// ErrorDetails reads the details the error was created with by NewError into details, a pointer. Returns
+// false if there are none.
+func ErrorDetails(err error, details interface{}) (bool, error) {
+	output, ok := errorOutputDetails(err)
+	if !ok || output["Details"] == nil {
+		return false, nil
+	}
+
+	if err := convertJSON(output["Details"], details); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// errorOutputDetails returns the error output kept in the details of a CustomError
+func errorOutputDetails(err error) (map[string]interface{}, bool) {
+	var customErr *cadence.CustomError
+	if !errors.As(err, &customErr) || !customErr.HasDetails() {
+		return nil, false
+	}
+
+	// Decode into an interface, other types fail or panic if the details are not an error output
+	var details interface{}
+	if customErr.Details(&details) != nil {
+		return nil, false
+	}
+
+	output, ok := details.(map[string]interface{})
+	return output, ok
+}
+
+// newStatesError returns a named error without details, it is a *cadence.CustomError like NewError
+func newStatesError(name string, cause string) *cadence.CustomError {
+	return NewError(name, cause, nil).(*cadence.CustomError)
+}
+
+// workflowError returns the error a failed execution returns from its workflow. Classified errors are
+// returned as a cadence.CustomError so callers, like a parent workflow, see the same name.
+func workflowError(err error) error {
+	name, ok := classifyError(err)
+	if !ok {
+		return err
+	}
+
+	var customErr *cadence.CustomError
+	if errors.As(err, &customErr) && customErr.Reason() == name {
+		return customErr
+	}
+	return newStatesError(name, err.Error())
+}
+
+// isCanceled returns true if the error is a cancellation of the state's work
+func isCanceled(err error) bool {
+	var canceledErr *cadence.CanceledError
+	return errors.As(err, &canceledErr)
+}
+
+// taskFailed names errors returned by a Task's handler States.TaskFailed unless they are already classified
+func taskFailed(err error) error {
+	if _, ok := classifyError(err); ok || isCanceled(err) {
+		return err
+	}
+	return newStatesError(StatesTaskFailed, err.Error())
+}
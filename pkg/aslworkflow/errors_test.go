@@ -0,0 +1,381 @@
+package aslworkflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/cadence"
+	"go.uber.org/cadence/workflow"
+)
+
+func TestNewError(t *testing.T) {
+	err := NewError("Order.OutOfStock", "item 42 is out of stock", map[string]interface{}{"item": 42})
+	assert.Equal(t, "Order.OutOfStock", ErrorName(err))
+	assert.Equal(t, "item 42 is out of stock", ErrorCause(err))
+
+	var details struct{ Item int }
+	ok, detailsErr := ErrorDetails(fmt.Errorf("State Error: %w", err), &details)
+	assert.NoError(t, detailsErr)
+	assert.True(t, ok)
+	assert.Equal(t, 42, details.Item)
+
+	// Errors without details, or with details that are not an error output
+	for _, err := range []error{
+		NewError("Order.OutOfStock", "", nil),
+		cadence.NewCustomError("MyError"),
+		cadence.NewCustomError("MyError", "not an error output"),
+		errors.New("plain"),
+	} {
+		ok, detailsErr := ErrorDetails(err, &details)
+		assert.NoError(t, detailsErr)
+		assert.False(t, ok, err.Error())
+	}
+
+	assert.Equal(t, "MyError", ErrorCause(cadence.NewCustomError("MyError", "not an error output")))
+}
+
+func TestErrorName(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{workflow.NewHeartbeatTimeoutError(), StatesTimeout},
+		{cadence.NewCustomError("MyError"), "MyError"},
+		{newStatesError(StatesNoChoiceMatched, "no match"), StatesNoChoiceMatched},
+		{fmt.Errorf("State Error: %w", newStatesError(StatesTaskFailed, "failed")), StatesTaskFailed},
+		{fmt.Errorf("State Error: %w", workflow.NewHeartbeatTimeoutError()), StatesTimeout},
+		{errors.New("plain"), "errorString"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, ErrorName(test.err), test.err.Error())
+	}
+}
+
+func TestTaskFailed(t *testing.T) {
+	err := taskFailed(errors.New("handler failed"))
+	assert.Equal(t, StatesTaskFailed, ErrorName(err))
+	assert.Equal(t, "handler failed", ErrorCause(err))
+
+	// Already classified errors keep their name
+	assert.Equal(t, StatesTimeout, ErrorName(taskFailed(workflow.NewHeartbeatTimeoutError())))
+	assert.Equal(t, "MyError", ErrorName(taskFailed(cadence.NewCustomError("MyError"))))
+}
+
+var taskFailedMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "example:activity:Fail",
+			"Catch": [
+				{
+					"ErrorEquals": ["States.TaskFailed"],
+					"ResultPath": "$.error",
+					"Next": "Failed"
+				}
+			],
+			"End": true
+		},
+		"Failed": {
+			"Type": "Pass",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Task_Failed_Catch() {
+	sm, err := FromJSON(taskFailedMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		return nil, errors.New("activity failed")
+	}
+	RegisterHandler(handler)
+
+	RegisterWorkflow("TestTaskFailedWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestTaskFailedWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{"Error": StatesTaskFailed, "Cause": "activity failed"}, result["error"])
+}
+
+var branchFailedMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Parallel",
+			"Branches": [
+				{
+					"StartAt": "Branch1",
+					"States": {
+						"Branch1": {
+							"Type": "Task",
+							"Resource": "example:activity:Fail",
+							"End": true
+						}
+					}
+				}
+			],
+			"Catch": [
+				{
+					"ErrorEquals": ["States.BranchFailed"],
+					"ResultPath": "$.error",
+					"Next": "Failed"
+				}
+			],
+			"End": true
+		},
+		"Failed": {
+			"Type": "Pass",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Branch_Failed_Catch() {
+	sm, err := FromJSON(branchFailedMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		return nil, errors.New("activity failed")
+	}
+	RegisterHandler(handler)
+
+	RegisterWorkflow("TestBranchFailedWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestBranchFailedWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	if s.Contains(result, "error") {
+		s.Equal(StatesBranchFailed, result["error"].(map[string]interface{})["Error"])
+	}
+}
+
+var noChoiceMatchedMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Choice",
+			"Choices": [
+				{
+					"Variable": "$.value",
+					"NumericEquals": 1,
+					"Next": "Example2"
+				}
+			]
+		},
+		"Example2": {
+			"Type": "Pass",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_No_Choice_Matched() {
+	sm, err := FromJSON(noChoiceMatchedMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestNoChoiceMatchedWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestNoChoiceMatchedWorkflow", map[string]interface{}{"value": 2})
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	err = s.env.GetWorkflowError()
+	if s.Error(err) {
+		customErr, ok := err.(*cadence.CustomError)
+		if s.True(ok) {
+			s.Equal(StatesNoChoiceMatched, customErr.Reason())
+		}
+	}
+}
+
+var namedErrorMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "example:activity:TestOutOfStock",
+			"Catch": [
+				{
+					"ErrorEquals": ["Order.OutOfStock"],
+					"ResultPath": "$.error",
+					"Next": "Failed"
+				}
+			],
+			"End": true
+		},
+		"Failed": {
+			"Type": "Pass",
+			"End": true
+		}
+	}
+}
+`)
+
+var registerOutOfStockActivityOnce sync.Once
+
+func (s *UnitTestSuite) Test_Workflow_Named_Error_Catch() {
+	registerOutOfStockActivityOnce.Do(func() {
+		RegisterActivity("example:activity:TestOutOfStock", func(ctx context.Context, input interface{}) (interface{}, error) {
+			return nil, NewError("Order.OutOfStock", "item 42 is out of stock", map[string]interface{}{"item": 42})
+		})
+	})
+
+	sm, err := FromJSON(namedErrorMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	// The error has been serialized by Cadence by the time the handler sees it
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		err := workflow.ExecuteActivity(ctx, resource, input).Get(ctx, nil)
+
+		var details map[string]interface{}
+		ok, detailsErr := ErrorDetails(err, &details)
+		s.NoError(detailsErr)
+		s.True(ok)
+		s.Equal(map[string]interface{}{"item": 42.0}, details)
+		return nil, err
+	}
+	RegisterHandler(handler)
+
+	RegisterWorkflow("TestNamedErrorWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestNamedErrorWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{"Error": "Order.OutOfStock", "Cause": "item 42 is out of stock"}, result["error"])
+}
+
+var canceledBranchMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Parallel",
+			"Branches": [
+				{
+					"StartAt": "Fail",
+					"States": {
+						"Fail": {
+							"Type": "Task",
+							"Resource": "example:activity:Fail",
+							"End": true
+						}
+					}
+				},
+				{
+					"StartAt": "Slow",
+					"States": {
+						"Slow": {
+							"Type": "Task",
+							"Resource": "example:activity:Slow",
+							"Retry": [
+								{
+									"ErrorEquals": ["States.ALL"],
+									"MaxAttempts": 3
+								}
+							],
+							"Catch": [
+								{
+									"ErrorEquals": ["States.ALL"],
+									"Next": "Recovered"
+								}
+							],
+							"End": true
+						},
+						"Recovered": {
+							"Type": "Task",
+							"Resource": "example:activity:Recovered",
+							"End": true
+						}
+					}
+				}
+			],
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Canceled_Branch_Not_Retried() {
+	sm, err := FromJSON(canceledBranchMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	calls := map[string]int{}
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		calls[resource]++
+		switch resource {
+		case "example:activity:Fail":
+			// Let the other branch start before failing
+			if err := workflow.Sleep(ctx, time.Second); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("branch failed")
+		case "example:activity:Slow":
+			return nil, workflow.Sleep(ctx, time.Hour)
+		}
+		return nil, nil
+	}
+	RegisterHandler(handler)
+
+	RegisterWorkflow("TestCanceledBranchWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestCanceledBranchWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	customErr, ok := s.env.GetWorkflowError().(*cadence.CustomError)
+	if s.True(ok) {
+		s.Equal(StatesBranchFailed, customErr.Reason())
+	}
+
+	s.Equal(1, calls["example:activity:Slow"])
+	s.Equal(0, calls["example:activity:Recovered"])
+}
+
+func TestTaskFailedCanceled(t *testing.T) {
+	err := fmt.Errorf("State Error: %w", cadence.NewCanceledError())
+	assert.Equal(t, err, taskFailed(err))
+	assert.True(t, isCanceled(err))
+}
@@ -0,0 +1,126 @@
+package aslworkflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"go.uber.org/cadence/activity"
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// Task states with the exec:command Resource run a command on the worker from an activity. Command and
+// Args are read from the state's Parameters, Input is written to stdin as JSON and stdout is parsed as
+// JSON for the result. The command must be in the allowlist the worker registered the activity with.
+//
+//	aslworkflow.RegisterExecCommandActivity("/usr/local/bin/transform")
+//
+//	"Transform": {
+//		"Type": "Task",
+//		"Resource": "exec:command",
+//		"Parameters": {
+//			"Command": "/usr/local/bin/transform",
+//			"Args": ["--format", "csv"],
+//			"Input.$": "$.records"
+//		},
+//		"HeartbeatSeconds": 30,
+//		"End": true
+//	}
+//
+// A non-zero exit code fails with Exec.ExitCode.<code>, e.g. Exec.ExitCode.1, with stderr as the cause.
+// The process is killed if the activity is cancelled or times out.
+
+const ExecCommandResource = "exec:command"
+
+const ExecCommandNotAllowed = "Exec.CommandNotAllowed"
+const ExecInvalidOutput = "Exec.InvalidOutput"
+
+// ExecCommand is the input of the exec:command activity
+type ExecCommand struct {
+	Command string
+	Args    []string    `json:",omitempty"`
+	Input   interface{} `json:",omitempty"`
+}
+
+// maxExecCause limits how much of stderr is kept as the cause of a failure
+const maxExecCause = 4096
+
+// RegisterExecCommandActivity registers the exec:command activity, only the listed commands can be run
+func RegisterExecCommandActivity(allowlist ...string) {
+	allowed := map[string]bool{}
+	for _, command := range allowlist {
+		allowed[command] = true
+	}
+
+	activityFunc := func(ctx context.Context, command ExecCommand) (interface{}, error) {
+		return execCommand(ctx, allowed, command)
+	}
+	activity.RegisterWithOptions(activityFunc, activity.RegisterOptions{Name: ExecCommandResource})
+}
+
+func executeExecCommand(ctx workflow.Context, s *TaskState, resource string, input interface{}) (interface{}, error) {
+	var result interface{}
+	err := workflow.ExecuteActivity(ctx, ExecCommandResource, input).Get(ctx, &result)
+	return result, err
+}
+
+func execCommand(ctx context.Context, allowed map[string]bool, command ExecCommand) (interface{}, error) {
+	if !allowed[command.Command] {
+		return nil, newStatesError(ExecCommandNotAllowed, fmt.Sprintf("command %q is not in the allowlist", command.Command))
+	}
+
+	stdin, err := json.Marshal(command.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	// CommandContext kills the process when the activity is cancelled, cancellation is delivered with heartbeats
+	cmd := exec.CommandContext(ctx, command.Command, command.Args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	stopHeartbeats := startHeartbeats(ctx, nil)
+	err = cmd.Run()
+	stopHeartbeats()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		name := fmt.Sprintf("Exec.ExitCode.%d", exitErr.ExitCode())
+		return nil, newStatesError(name, execCause(stderr.String()))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return nil, nil
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return nil, newStatesError(ExecInvalidOutput, fmt.Sprintf("stdout is not JSON: %v", err))
+	}
+	return result, nil
+}
+
+// execCause returns the end of stderr, where the reason for the failure is most likely to be
+func execCause(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if len(stderr) > maxExecCause {
+		return stderr[len(stderr)-maxExecCause:]
+	}
+	return stderr
+}
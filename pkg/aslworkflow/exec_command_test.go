@@ -0,0 +1,87 @@
+package aslworkflow
+
+import (
+	"sync"
+
+	"go.uber.org/cadence"
+)
+
+var registerExecCommandOnce sync.Once
+
+var execCommandMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "exec:command",
+			"Parameters": {
+				"Command.$": "$.command",
+				"Args.$": "$.args",
+				"Input.$": "$.records"
+			},
+			"HeartbeatSeconds": 10,
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) executeCommandWorkflow(workflowName string, input map[string]interface{}) {
+	registerExecCommandOnce.Do(func() {
+		RegisterExecCommandActivity("sh")
+	})
+
+	sm, err := FromJSON(execCommandMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow(workflowName, *sm)
+	s.env.ExecuteWorkflow(workflowName, input)
+	s.True(s.env.IsWorkflowCompleted())
+}
+
+func (s *UnitTestSuite) Test_Workflow_Exec_Command() {
+	s.executeCommandWorkflow("TestExecCommandWorkflow", map[string]interface{}{
+		"command": "sh",
+		"args":    []interface{}{"-c", `read input; echo "{\"stdin\": $input}"`},
+		"records": []interface{}{1, 2},
+	})
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{"stdin": []interface{}{1.0, 2.0}}, result)
+}
+
+func (s *UnitTestSuite) Test_Workflow_Exec_Command_Exit_Code() {
+	s.executeCommandWorkflow("TestExecCommandExitCodeWorkflow", map[string]interface{}{
+		"command": "sh",
+		"args":    []interface{}{"-c", "echo failed >&2; exit 3"},
+		"records": nil,
+	})
+
+	customErr, ok := s.env.GetWorkflowError().(*cadence.CustomError)
+	if s.True(ok) {
+		s.Equal("Exec.ExitCode.3", customErr.Reason())
+
+		var details map[string]interface{}
+		s.NoError(customErr.Details(&details))
+		s.Equal("failed", details["Cause"])
+	}
+}
+
+func (s *UnitTestSuite) Test_Workflow_Exec_Command_Not_Allowed() {
+	s.executeCommandWorkflow("TestExecCommandNotAllowedWorkflow", map[string]interface{}{
+		"command": "rm",
+		"args":    []interface{}{"-rf", "/tmp/example"},
+		"records": nil,
+	})
+
+	customErr, ok := s.env.GetWorkflowError().(*cadence.CustomError)
+	if s.True(ok) {
+		s.Equal(ExecCommandNotAllowed, customErr.Reason())
+	}
+}
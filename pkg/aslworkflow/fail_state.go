@@ -3,26 +3,64 @@ package aslworkflow
 import (
 	"fmt"
 
+	"github.com/checkr/states-language-cadence/pkg/jsonpath"
 	"github.com/coinbase/step/utils/is"
 	"github.com/coinbase/step/utils/to"
-	"go.uber.org/cadence"
 	"go.uber.org/cadence/workflow"
 )
 
+//
+// FailState stops the execution with an error named by Error and described by Cause. ErrorPath and
+// CausePath select them from the state input, or the context object with $$, instead.
+//
+//	"Fail": {
+//		"Type": "Fail",
+//		"ErrorPath": "$.error.name",
+//		"Cause": "The order could not be processed"
+//	}
+
 type FailState struct {
 	stateStr // Include Defaults
 
 	Type    *string
 	Comment *string `json:",omitempty"`
 
-	Error *string `json:",omitempty"`
-	Cause *string `json:",omitempty"`
+	Error     *string        `json:",omitempty"`
+	ErrorPath *jsonpath.Path `json:",omitempty"`
+	Cause     *string        `json:",omitempty"`
+	CausePath *jsonpath.Path `json:",omitempty"`
+}
+
+func (s *FailState) Execute(ctx workflow.Context, input interface{}) (output interface{}, next *string, err error) {
+	name, err := failString(ctx, s.Error, s.ErrorPath, input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v ErrorPath Error: %w", errorPrefix(s), err)
+	}
+
+	cause, err := failString(ctx, s.Cause, s.CausePath, input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v CausePath Error: %w", errorPrefix(s), err)
+	}
+
+	return nil, nil, newStatesError(name, cause)
 }
 
-func (s *FailState) Execute(_ workflow.Context, input interface{}) (output interface{}, next *string, err error) {
-	return nil, nil, cadence.NewCustomError(
-		"Fail",
-		errorOutput(s.Error, s.Cause))
+// failString returns the value if it is set, otherwise the string selected by the path
+func failString(ctx workflow.Context, value *string, path *jsonpath.Path, input interface{}) (string, error) {
+	if path == nil {
+		return to.Strs(value), nil
+	}
+
+	selected, err := getPath(ctx, path, input)
+	if err != nil {
+		return "", err
+	}
+
+	str, ok := selected.(string)
+	if !ok {
+		return "", fmt.Errorf("must select a string")
+	}
+	return str, nil
 }
 
 func (s *FailState) Validate() error {
@@ -32,10 +70,26 @@ func (s *FailState) Validate() error {
 		return fmt.Errorf("%v %v", errorPrefix(s), err)
 	}
 
-	if is.EmptyStr(s.Error) {
+	if is.EmptyStr(s.Error) && s.ErrorPath == nil {
 		return fmt.Errorf("%v %v", errorPrefix(s), "must contain Error")
 	}
 
+	if s.Error != nil && s.ErrorPath != nil {
+		return fmt.Errorf("%v Only One (Error,ErrorPath)", errorPrefix(s))
+	}
+
+	if s.Cause != nil && s.CausePath != nil {
+		return fmt.Errorf("%v Only One (Cause,CausePath)", errorPrefix(s))
+	}
+
+	if err := isReferencePathValid("ErrorPath", s.ErrorPath); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isReferencePathValid("CausePath", s.CausePath); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
 	return nil
 }
 
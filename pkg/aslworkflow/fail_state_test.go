@@ -1,6 +1,9 @@
 package aslworkflow
 
 import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
 	"go.uber.org/cadence"
 )
 
@@ -48,3 +51,59 @@ func (s *UnitTestSuite) Test_Workflow_Fail_State() {
 	s.Equal("ExampleError", details["Error"])
 	s.Equal("This is an example error", details["Cause"])
 }
+
+var failPathMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Fail",
+			"ErrorPath": "$.error",
+			"CausePath": "$$.State.Name"
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Fail_State_Path() {
+	sm, err := FromJSON(failPathMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestFailPathWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestFailPathWorkflow", map[string]interface{}{"error": "ExampleError"})
+	s.True(s.env.IsWorkflowCompleted())
+	err = s.env.GetWorkflowError()
+
+	customErr, ok := err.(*cadence.CustomError)
+	if s.True(ok) {
+		s.Equal("ExampleError", customErr.Reason())
+
+		var details map[string]interface{}
+		s.NoError(customErr.Details(&details))
+		s.Equal(map[string]interface{}{"Error": "ExampleError", "Cause": "Example1"}, details)
+	}
+}
+
+func TestFailStateValidate(t *testing.T) {
+	_, err := FromJSON([]byte(`{
+		"StartAt": "Example1",
+		"States": {"Example1": {"Type": "Fail", "Error": "ExampleError", "ErrorPath": "$.error"}}
+	}`))
+	assert.Error(t, err)
+
+	_, err = FromJSON([]byte(`{
+		"StartAt": "Example1",
+		"States": {"Example1": {"Type": "Fail", "ErrorPath": "$.errors[*]"}}
+	}`))
+	assert.Error(t, err)
+
+	_, err = FromJSON([]byte(`{
+		"StartAt": "Example1",
+		"States": {"Example1": {"Type": "Fail", "Cause": "cause"}}
+	}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,210 @@
+package aslworkflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/cadence"
+	"go.uber.org/cadence/activity"
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// Task states with the http:invoke Resource make an HTTP request from an activity. The request is
+// built from the state's Parameters and the result is the response. Responses with a status outside
+// 2xx fail with Http.4xx, Http.5xx etc so Retry and Catch can match them. Workers register the activity
+// with the client to make requests with, nil for http.DefaultClient.
+//
+//	aslworkflow.RegisterHTTPInvokeActivity(&http.Client{Timeout: time.Minute})
+//
+//	"GetOrder": {
+//		"Type": "Task",
+//		"Resource": "http:invoke",
+//		"Parameters": {
+//			"Method": "GET",
+//			"Url.$": "States.Format('https://example.com/orders/{}', $.orderId)",
+//			"Headers": {"Authorization.$": "$.token"},
+//			"Query": {"expand": "items"}
+//		},
+//		"TimeoutSeconds": 30,
+//		"ResultSelector": {"order.$": "$.Body"},
+//		"End": true
+//	}
+//
+// The result is {"StatusCode": 200, "Headers": {...}, "Body": ...}, where Body is parsed if it is JSON.
+
+const HTTPInvokeResource = "http:invoke"
+
+// HTTPRequest is the input of the http:invoke activity
+type HTTPRequest struct {
+	Method  string                 `json:",omitempty"` // Defaults to GET
+	Url     string                 `json:",omitempty"`
+	Headers map[string]interface{} `json:",omitempty"` // Values are strings or arrays of strings
+	Query   map[string]interface{} `json:",omitempty"` // Values are strings, numbers, booleans or arrays of them
+	Body    interface{}            `json:",omitempty"` // Strings are sent as is, other values as JSON
+}
+
+// HTTPResponse is the result of the http:invoke activity
+type HTTPResponse struct {
+	StatusCode int
+	Headers    map[string]interface{}
+	Body       interface{}
+}
+
+// RegisterHTTPInvokeActivity registers the http:invoke activity, requests are made with the client
+func RegisterHTTPInvokeActivity(client *http.Client) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	activityFunc := func(ctx context.Context, input interface{}) (*HTTPResponse, error) {
+		return httpInvoke(ctx, client, input)
+	}
+	activity.RegisterWithOptions(activityFunc, activity.RegisterOptions{Name: HTTPInvokeResource})
+}
+
+func executeHTTPInvoke(ctx workflow.Context, s *TaskState, resource string, input interface{}) (interface{}, error) {
+	var result interface{}
+	err := workflow.ExecuteActivity(ctx, HTTPInvokeResource, input).Get(ctx, &result)
+	return result, err
+}
+
+// httpInvoke makes the request, recording heartbeats while it waits for the response
+func httpInvoke(ctx context.Context, client *http.Client, input interface{}) (*HTTPResponse, error) {
+	req, err := newHTTPRequest(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	stopHeartbeats := startHeartbeats(ctx, nil)
+	defer stopHeartbeats()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response, err := newHTTPResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		name := fmt.Sprintf("Http.%dxx", resp.StatusCode/100)
+		cause := fmt.Sprintf("%v %v://%v%v returned %v", req.Method, req.URL.Scheme, req.URL.Host, req.URL.Path, resp.Status)
+
+		details := errorOutput(&name, &cause)
+		details["Response"] = response
+		return nil, cadence.NewCustomError(name, details)
+	}
+
+	return response, nil
+}
+
+func newHTTPRequest(ctx context.Context, input interface{}) (*http.Request, error) {
+	// Round trip through JSON to read the Parameters into an HTTPRequest
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var r HTTPRequest
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return nil, fmt.Errorf("invalid http:invoke Parameters: %w", err)
+	}
+
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+
+	u, err := url.Parse(r.Url)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid http:invoke Url %q", r.Url)
+	}
+
+	query := u.Query()
+	for key, value := range r.Query {
+		for _, v := range stringValues(value) {
+			query.Add(key, v)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	var body io.Reader
+	isJSON := false
+	switch b := r.Body.(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(b)
+	default:
+		raw, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(raw)
+		isJSON = true
+	}
+
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(r.Method), u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range r.Headers {
+		for _, v := range stringValues(value) {
+			req.Header.Add(key, v)
+		}
+	}
+
+	if isJSON && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
+
+func newHTTPResponse(resp *http.Response) (*HTTPResponse, error) {
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string]interface{}{}
+	for key, values := range resp.Header {
+		headers[key] = strings.Join(values, ", ")
+	}
+
+	// Parse JSON bodies, anything else is returned as a string
+	var body interface{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &body); err != nil {
+			body = string(raw)
+		}
+	}
+
+	return &HTTPResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    headers,
+		Body:       body,
+	}, nil
+}
+
+// stringValues returns the value, or each value of an array, formatted as strings
+func stringValues(value interface{}) []string {
+	if values, ok := value.([]interface{}); ok {
+		var output []string
+		for _, v := range values {
+			output = append(output, fmt.Sprint(v))
+		}
+		return output
+	}
+	return []string{fmt.Sprint(value)}
+}
@@ -0,0 +1,146 @@
+package aslworkflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+var httpInvokeMachine = `
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "http:invoke",
+			"Parameters": {
+				"Method": "POST",
+				"Url.$": "States.Format('%s/orders/{}', $.id)",
+				"Headers": {"X-Example.$": "$.id"},
+				"Query": {"expand": ["items", "total"]},
+				"Body": {"quantity": 2}
+			},
+			"TimeoutSeconds": 10,
+			"ResultSelector": {"status.$": "$.StatusCode", "order.$": "$.Body"},
+			"Catch": [
+				{
+					"ErrorEquals": ["Http.4xx"],
+					"ResultPath": "$.error",
+					"Next": "NotFound"
+				}
+			],
+			"End": true
+		},
+		"NotFound": {
+			"Type": "Pass",
+			"End": true
+		}
+	}
+}
+`
+
+var registerHTTPInvokeOnce sync.Once
+
+func newHTTPInvokeServer(s *UnitTestSuite) *httptest.Server {
+	registerHTTPInvokeOnce.Do(func() {
+		RegisterHTTPInvokeActivity(nil)
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orders/example" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not found"))
+			return
+		}
+
+		s.Equal(http.MethodPost, r.Method)
+		s.Equal("example", r.Header.Get("X-Example"))
+		s.Equal("application/json", r.Header.Get("Content-Type"))
+		s.Equal([]string{"items", "total"}, r.URL.Query()["expand"])
+
+		body, _ := ioutil.ReadAll(r.Body)
+		s.JSONEq(`{"quantity": 2}`, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": "example", "quantity": 2})
+	}))
+}
+
+func (s *UnitTestSuite) Test_Workflow_HTTP_Invoke() {
+	server := newHTTPInvokeServer(s)
+	defer server.Close()
+
+	sm, err := FromJSON([]byte(fmt.Sprintf(httpInvokeMachine, server.URL)))
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestHTTPInvokeWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestHTTPInvokeWorkflow", map[string]interface{}{"id": "example"})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{
+		"status": 200.0,
+		"order":  map[string]interface{}{"id": "example", "quantity": 2.0},
+	}, result)
+}
+
+func (s *UnitTestSuite) Test_Workflow_HTTP_Invoke_Error() {
+	server := newHTTPInvokeServer(s)
+	defer server.Close()
+
+	sm, err := FromJSON([]byte(fmt.Sprintf(httpInvokeMachine, server.URL)))
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestHTTPInvokeErrorWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestHTTPInvokeErrorWorkflow", map[string]interface{}{"id": "missing"})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{
+		"Error": "Http.4xx",
+		"Cause": "POST " + server.URL + "/orders/missing returned 404 Not Found",
+	}, result["error"])
+}
+
+func (s *UnitTestSuite) Test_HTTP_Invoke_Activity() {
+	server := newHTTPInvokeServer(s)
+	defer server.Close()
+
+	env := s.NewTestActivityEnvironment()
+
+	_, err := env.ExecuteActivity(HTTPInvokeResource, map[string]interface{}{"Url": "not a url"})
+	s.Error(err)
+
+	value, err := env.ExecuteActivity(HTTPInvokeResource, map[string]interface{}{
+		"Method": "POST",
+		"Url":    server.URL + "/orders/example",
+		"Headers": map[string]interface{}{
+			"X-Example": "example",
+		},
+		"Query": map[string]interface{}{"expand": []interface{}{"items", "total"}},
+		"Body":  map[string]interface{}{"quantity": 2},
+	})
+	if s.NoError(err) {
+		var response HTTPResponse
+		s.NoError(value.Get(&response))
+		s.Equal(200, response.StatusCode)
+		s.Equal("application/json", response.Headers["Content-Type"])
+	}
+}
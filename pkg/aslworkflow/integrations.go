@@ -0,0 +1,43 @@
+package aslworkflow
+
+import (
+	"strings"
+
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// Integrations are Task resources run by the interpreter itself instead of a TaskHandler, because
+// they need more than the resource and input, e.g. options from the state definition.
+//
+//	cadence:workflow:<Name>       start a child workflow and return its IDs
+//	cadence:workflow:<Name>.sync  run a child workflow and return its result
+//	asl:statemachine:<Name>       run a registered state machine inline and return its output
+//	http:invoke                   make an HTTP request from an activity
+//	exec:command                  run an allowed command on the worker from an activity
+//
+// The .waitForTaskToken suffix is removed from the resource before it is matched.
+
+// integration runs a Task state whose Resource is handled by the interpreter
+type integration func(ctx workflow.Context, s *TaskState, resource string, input interface{}) (interface{}, error)
+
+// builtinIntegration returns the integration for a resource, false if it must be run by a TaskHandler
+func builtinIntegration(resource string) (integration, bool) {
+	switch {
+	case strings.HasPrefix(resource, ChildWorkflowResourcePrefix):
+		return executeChildWorkflow, true
+	case strings.HasPrefix(resource, StateMachineResourcePrefix):
+		return executeStateMachine, true
+	case resource == HTTPInvokeResource:
+		return executeHTTPInvoke, true
+	case resource == ExecCommandResource:
+		return executeExecCommand, true
+	}
+	return nil, false
+}
+
+// isBuiltinResource returns true if the resource is run by the interpreter
+func isBuiltinResource(resource string) bool {
+	_, ok := builtinIntegration(resource)
+	return ok
+}
@@ -0,0 +1,778 @@
+package aslworkflow
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math"
+	mathrand "math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/checkr/states-language-cadence/pkg/jsonpath"
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// Intrinsic functions can be used as the value of any "key.$" field in Parameters or ResultSelector
+// templates in place of a JSON path.
+//
+//	"Parameters": {
+//		"greeting.$": "States.Format('Hello, {}!', $.name)",
+//		"id.$": "States.UUID()"
+//	}
+
+const intrinsicPrefix = "States."
+
+// maxArrayRangeLength is the largest array States.ArrayRange will generate
+const maxArrayRangeLength = 1000
+
+type intrinsicFunction func(ctx workflow.Context, args []interface{}) (interface{}, error)
+
+var intrinsicFunctions = map[string]intrinsicFunction{
+	"States.Format":         intrinsicFormat,
+	"States.StringToJson":   intrinsicStringToJSON,
+	"States.JsonToString":   intrinsicJSONToString,
+	"States.Array":          intrinsicArray,
+	"States.ArrayPartition": intrinsicArrayPartition,
+	"States.ArrayContains":  intrinsicArrayContains,
+	"States.ArrayRange":     intrinsicArrayRange,
+	"States.ArrayGetItem":   intrinsicArrayGetItem,
+	"States.ArrayLength":    intrinsicArrayLength,
+	"States.ArrayUnique":    intrinsicArrayUnique,
+	"States.Base64Encode":   intrinsicBase64Encode,
+	"States.Base64Decode":   intrinsicBase64Decode,
+	"States.Hash":           intrinsicHash,
+	"States.JsonMerge":      intrinsicJSONMerge,
+	"States.MathRandom":     intrinsicMathRandom,
+	"States.MathAdd":        intrinsicMathAdd,
+	"States.StringSplit":    intrinsicStringSplit,
+	"States.UUID":           intrinsicUUID,
+}
+
+// intrinsicCall is a parsed intrinsic function, e.g. States.Format('{}', $.name)
+type intrinsicCall struct {
+	name string
+	args []*intrinsicArg
+}
+
+// intrinsicArg is exactly one of a literal value, a path into the input, or a nested call
+type intrinsicArg struct {
+	literal interface{}
+	path    *jsonpath.Path
+	call    *intrinsicCall
+}
+
+func isIntrinsic(value string) bool {
+	return strings.HasPrefix(value, intrinsicPrefix)
+}
+
+// parseIntrinsic parses the full intrinsic function string
+func parseIntrinsic(value string) (*intrinsicCall, error) {
+	p := &intrinsicParser{input: value}
+
+	call, err := p.parseCall()
+	if err != nil {
+		return nil, fmt.Errorf("Bad intrinsic function %q: %w", value, err)
+	}
+
+	p.skipSpaces()
+	if !p.done() {
+		return nil, fmt.Errorf("Bad intrinsic function %q: unexpected %q after call", value, p.input[p.pos:])
+	}
+
+	return call, nil
+}
+
+func (c *intrinsicCall) evaluate(ctx workflow.Context, input interface{}) (interface{}, error) {
+	fn := intrinsicFunctions[c.name]
+
+	args := make([]interface{}, len(c.args))
+	for i, arg := range c.args {
+		value, err := arg.evaluate(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+
+	result, err := fn(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", c.name, err)
+	}
+
+	return result, nil
+}
+
+func (a *intrinsicArg) evaluate(ctx workflow.Context, input interface{}) (interface{}, error) {
+	switch {
+	case a.call != nil:
+		return a.call.evaluate(ctx, input)
+	case a.path != nil:
+		return getPath(ctx, a.path, input)
+	default:
+		return a.literal, nil
+	}
+}
+
+//////
+// Parser
+//////
+
+type intrinsicParser struct {
+	input string
+	pos   int
+}
+
+func (p *intrinsicParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *intrinsicParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *intrinsicParser) skipSpaces() {
+	for !p.done() && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *intrinsicParser) parseCall() (*intrinsicCall, error) {
+	start := p.pos
+	for !p.done() && p.peek() != '(' {
+		p.pos++
+	}
+
+	name := strings.TrimSpace(p.input[start:p.pos])
+	if _, ok := intrinsicFunctions[name]; !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+
+	if p.done() {
+		return nil, fmt.Errorf("missing ( after %q", name)
+	}
+	p.pos++ // (
+
+	call := &intrinsicCall{name: name}
+
+	p.skipSpaces()
+	if p.peek() == ')' {
+		p.pos++
+		return call, nil
+	}
+
+	for {
+		p.skipSpaces()
+		arg, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+
+		p.skipSpaces()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return call, nil
+		default:
+			return nil, fmt.Errorf("expected , or ) at position %d", p.pos)
+		}
+	}
+}
+
+func (p *intrinsicParser) parseArg() (*intrinsicArg, error) {
+	switch {
+	case p.peek() == '\'':
+		str, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &intrinsicArg{literal: str}, nil
+
+	case p.peek() == '$':
+		path, err := jsonpath.NewPath(p.scanToken())
+		if err != nil {
+			return nil, err
+		}
+		return &intrinsicArg{path: path}, nil
+
+	case strings.HasPrefix(p.input[p.pos:], intrinsicPrefix):
+		call, err := p.parseCall()
+		if err != nil {
+			return nil, err
+		}
+		return &intrinsicArg{call: call}, nil
+	}
+
+	token := p.scanToken()
+	switch token {
+	case "":
+		return nil, fmt.Errorf("missing argument at position %d", p.pos)
+	case "null":
+		return &intrinsicArg{literal: nil}, nil
+	case "true":
+		return &intrinsicArg{literal: true}, nil
+	case "false":
+		return &intrinsicArg{literal: false}, nil
+	}
+
+	number, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad argument %q", token)
+	}
+	return &intrinsicArg{literal: number}, nil
+}
+
+// parseString reads a single quoted string, \ escapes the next character
+func (p *intrinsicParser) parseString() (string, error) {
+	p.pos++ // opening '
+
+	var sb strings.Builder
+	for !p.done() {
+		c := p.input[p.pos]
+		p.pos++
+
+		switch c {
+		case '\\':
+			if p.done() {
+				return "", fmt.Errorf("unterminated escape")
+			}
+			// Keep escaped braces escaped so States.Format can tell them apart from placeholders
+			if next := p.input[p.pos]; next == '{' || next == '}' {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(p.input[p.pos])
+			p.pos++
+		case '\'':
+			return sb.String(), nil
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return "", fmt.Errorf("unterminated string")
+}
+
+// scanToken reads until the next top level , or ) skipping over brackets and quoted strings in paths
+func (p *intrinsicParser) scanToken() string {
+	start := p.pos
+	depth := 0
+	var quote byte
+
+	for !p.done() {
+		c := p.input[p.pos]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				p.pos++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']':
+			depth--
+		case c == ')' && depth == 0, c == ',' && depth == 0:
+			return strings.TrimSpace(p.input[start:p.pos])
+		case c == ')':
+			depth--
+		}
+		p.pos++
+	}
+
+	return strings.TrimSpace(p.input[start:p.pos])
+}
+
+//////
+// Functions
+//////
+
+func intrinsicArgCount(args []interface{}, min int, max int) error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		if min == max {
+			return fmt.Errorf("expected %d arguments got %d", min, len(args))
+		}
+		return fmt.Errorf("unexpected number of arguments %d", len(args))
+	}
+	return nil
+}
+
+func intrinsicString(arg interface{}) (string, error) {
+	str, ok := arg.(string)
+	if !ok {
+		return "", fmt.Errorf("expected string argument got %T", arg)
+	}
+	return str, nil
+}
+
+func intrinsicNumber(arg interface{}) (float64, error) {
+	switch number := arg.(type) {
+	case float64:
+		return number, nil
+	case int:
+		return float64(number), nil
+	}
+	return 0, fmt.Errorf("expected number argument got %T", arg)
+}
+
+func intrinsicInteger(arg interface{}) (int, error) {
+	number, err := intrinsicNumber(arg)
+	if err != nil {
+		return 0, err
+	}
+	if number != math.Trunc(number) {
+		return 0, fmt.Errorf("expected integer argument got %v", number)
+	}
+	return int(number), nil
+}
+
+func intrinsicArrayArg(arg interface{}) ([]interface{}, error) {
+	array, ok := arg.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected array argument got %T", arg)
+	}
+	return array, nil
+}
+
+func intrinsicFormat(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 1, -1); err != nil {
+		return nil, err
+	}
+
+	template, err := intrinsicString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	values := args[1:]
+	var sb strings.Builder
+	for i := 0; i < len(template); i++ {
+		switch {
+		case template[i] == '\\' && i+1 < len(template) && (template[i+1] == '{' || template[i+1] == '}'):
+			i++
+			sb.WriteByte(template[i])
+		case strings.HasPrefix(template[i:], "{}"):
+			if len(values) == 0 {
+				return nil, fmt.Errorf("more placeholders than arguments")
+			}
+			switch value := values[0].(type) {
+			case string:
+				sb.WriteString(value)
+			case map[string]interface{}, []interface{}:
+				return nil, fmt.Errorf("cannot format %T", value)
+			default:
+				raw, err := json.Marshal(value)
+				if err != nil {
+					return nil, err
+				}
+				sb.Write(raw)
+			}
+			values = values[1:]
+			i++
+		default:
+			sb.WriteByte(template[i])
+		}
+	}
+
+	if len(values) != 0 {
+		return nil, fmt.Errorf("more arguments than placeholders")
+	}
+
+	return sb.String(), nil
+}
+
+func intrinsicStringToJSON(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	str, err := intrinsicString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var output interface{}
+	if err := json.Unmarshal([]byte(str), &output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func intrinsicJSONToString(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+func intrinsicArray(_ workflow.Context, args []interface{}) (interface{}, error) {
+	return append([]interface{}{}, args...), nil
+}
+
+func intrinsicArrayPartition(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	array, err := intrinsicArrayArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := intrinsicInteger(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive")
+	}
+
+	chunks := []interface{}{}
+	for start := 0; start < len(array); start += size {
+		end := start + size
+		if end > len(array) {
+			end = len(array)
+		}
+		chunks = append(chunks, append([]interface{}{}, array[start:end]...))
+	}
+	return chunks, nil
+}
+
+func intrinsicArrayContains(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	array, err := intrinsicArrayArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range array {
+		if reflect.DeepEqual(item, args[1]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func intrinsicArrayRange(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 3, 3); err != nil {
+		return nil, err
+	}
+
+	var bounds [3]int
+	for i := range bounds {
+		n, err := intrinsicInteger(args[i])
+		if err != nil {
+			return nil, err
+		}
+		bounds[i] = n
+	}
+
+	start, end, step := bounds[0], bounds[1], bounds[2]
+	if step == 0 {
+		return nil, fmt.Errorf("step must not be 0")
+	}
+
+	array := []interface{}{}
+	for n := start; (step > 0 && n <= end) || (step < 0 && n >= end); n += step {
+		if len(array) == maxArrayRangeLength {
+			return nil, fmt.Errorf("range is larger than %d items", maxArrayRangeLength)
+		}
+		array = append(array, float64(n))
+	}
+	return array, nil
+}
+
+func intrinsicArrayGetItem(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	array, err := intrinsicArrayArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := intrinsicInteger(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(array) {
+		return nil, fmt.Errorf("index %d out of range", index)
+	}
+	return array[index], nil
+}
+
+func intrinsicArrayLength(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	array, err := intrinsicArrayArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return float64(len(array)), nil
+}
+
+func intrinsicArrayUnique(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	array, err := intrinsicArrayArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	unique := []interface{}{}
+	for _, item := range array {
+		found := false
+		for _, u := range unique {
+			if reflect.DeepEqual(item, u) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unique = append(unique, item)
+		}
+	}
+	return unique, nil
+}
+
+func intrinsicBase64Encode(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	str, err := intrinsicString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(str)), nil
+}
+
+func intrinsicBase64Decode(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	str, err := intrinsicString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, err
+	}
+	return string(decoded), nil
+}
+
+func intrinsicHash(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	data, err := intrinsicString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, err := intrinsicString(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var h hash.Hash
+	switch algorithm {
+	case "MD5":
+		h = md5.New()
+	case "SHA-1":
+		h = sha1.New()
+	case "SHA-256":
+		h = sha256.New()
+	case "SHA-384":
+		h = sha512.New384()
+	case "SHA-512":
+		h = sha512.New()
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm %q", algorithm)
+	}
+
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func intrinsicJSONMerge(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 3, 3); err != nil {
+		return nil, err
+	}
+
+	left, ok := args[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected object argument got %T", args[0])
+	}
+
+	right, ok := args[1].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected object argument got %T", args[1])
+	}
+
+	deep, ok := args[2].(bool)
+	if !ok {
+		return nil, fmt.Errorf("expected boolean argument got %T", args[2])
+	}
+
+	return mergeJSON(left, right, deep), nil
+}
+
+func mergeJSON(left map[string]interface{}, right map[string]interface{}, deep bool) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for key, value := range left {
+		merged[key] = value
+	}
+
+	for key, value := range right {
+		leftMap, leftOk := merged[key].(map[string]interface{})
+		rightMap, rightOk := value.(map[string]interface{})
+		if deep && leftOk && rightOk {
+			merged[key] = mergeJSON(leftMap, rightMap, deep)
+			continue
+		}
+		merged[key] = value
+	}
+
+	return merged
+}
+
+func intrinsicMathRandom(ctx workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 2, 3); err != nil {
+		return nil, err
+	}
+
+	start, err := intrinsicInteger(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := intrinsicInteger(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if end <= start {
+		return nil, fmt.Errorf("end must be greater than start")
+	}
+
+	if len(args) == 3 {
+		// A seeded generator is deterministic so it does not need to be recorded
+		seed, err := intrinsicInteger(args[2])
+		if err != nil {
+			return nil, err
+		}
+		return float64(start + mathrand.New(mathrand.NewSource(int64(seed))).Intn(end-start)), nil
+	}
+
+	var n int
+	encoded := workflow.SideEffect(ctx, func(ctx workflow.Context) interface{} {
+		return start + mathrand.Intn(end-start)
+	})
+	if err := encoded.Get(&n); err != nil {
+		return nil, err
+	}
+	return float64(n), nil
+}
+
+func intrinsicMathAdd(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	a, err := intrinsicNumber(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := intrinsicNumber(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return a + b, nil
+}
+
+func intrinsicStringSplit(_ workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	str, err := intrinsicString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	delimiters, err := intrinsicString(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// Every character in delimiters is a separator
+	fields := strings.FieldsFunc(str, func(r rune) bool {
+		return strings.ContainsRune(delimiters, r)
+	})
+
+	output := make([]interface{}, len(fields))
+	for i, f := range fields {
+		output[i] = f
+	}
+	return output, nil
+}
+
+func intrinsicUUID(ctx workflow.Context, args []interface{}) (interface{}, error) {
+	if err := intrinsicArgCount(args, 0, 0); err != nil {
+		return nil, err
+	}
+
+	var id string
+	encoded := workflow.SideEffect(ctx, func(ctx workflow.Context) interface{} {
+		return newUUID()
+	})
+	if err := encoded.Get(&id); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,182 @@
+package aslworkflow
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntrinsicFunctions(t *testing.T) {
+	input := map[string]interface{}{
+		"name":   "World",
+		"count":  float64(3),
+		"items":  []interface{}{"a", "b", "a", "c"},
+		"json":   `{"key":"value"}`,
+		"left":   map[string]interface{}{"a": float64(1), "nested": map[string]interface{}{"x": true}},
+		"right":  map[string]interface{}{"b": float64(2), "nested": map[string]interface{}{"y": true}},
+		"object": map[string]interface{}{"key": "value"},
+	}
+
+	tests := []struct {
+		intrinsic string
+		expected  interface{}
+	}{
+		{`States.Format('Hello, {}!', $.name)`, "Hello, World!"},
+		{`States.Format('{} has {} items \{\}', $.name, $.count)`, "World has 3 items {}"},
+		{`States.Format('it\'s {}', true)`, "it's true"},
+		{`States.StringToJson($.json)`, map[string]interface{}{"key": "value"}},
+		{`States.JsonToString($.object)`, `{"key":"value"}`},
+		{`States.Array('a', 1, $.name, null)`, []interface{}{"a", float64(1), "World", nil}},
+		{`States.ArrayPartition(States.Array(1, 2, 3, 4, 5), 2)`, []interface{}{
+			[]interface{}{float64(1), float64(2)},
+			[]interface{}{float64(3), float64(4)},
+			[]interface{}{float64(5)},
+		}},
+		{`States.ArrayContains($.items, 'b')`, true},
+		{`States.ArrayContains($.items, 'z')`, false},
+		{`States.ArrayRange(1, 9, 2)`, []interface{}{float64(1), float64(3), float64(5), float64(7), float64(9)}},
+		{`States.ArrayGetItem($.items, 1)`, "b"},
+		{`States.ArrayLength($.items)`, float64(4)},
+		{`States.ArrayUnique($.items)`, []interface{}{"a", "b", "c"}},
+		{`States.Base64Encode('Data to encode')`, "RGF0YSB0byBlbmNvZGU="},
+		{`States.Base64Decode('RGF0YSB0byBlbmNvZGU=')`, "Data to encode"},
+		{`States.Hash('input data', 'SHA-1')`, "aaff4a450a104cd177d28d18d74485e8cae074b7"},
+		{`States.JsonMerge($.left, $.right, false)`, map[string]interface{}{
+			"a": float64(1), "b": float64(2), "nested": map[string]interface{}{"y": true},
+		}},
+		{`States.JsonMerge($.left, $.right, true)`, map[string]interface{}{
+			"a": float64(1), "b": float64(2), "nested": map[string]interface{}{"x": true, "y": true},
+		}},
+		{`States.MathAdd($.count, -1)`, float64(2)},
+		{`States.StringSplit('1,2;3', ',;')`, []interface{}{"1", "2", "3"}},
+	}
+
+	for _, test := range tests {
+		call, err := parseIntrinsic(test.intrinsic)
+		if !assert.NoError(t, err, test.intrinsic) {
+			continue
+		}
+
+		output, err := call.evaluate(nil, input)
+		assert.NoError(t, err, test.intrinsic)
+		assert.Equal(t, test.expected, output, test.intrinsic)
+	}
+}
+
+func TestIntrinsicFunctionsInvalid(t *testing.T) {
+	for _, intrinsic := range []string{
+		`States.Unknown()`,
+		`States.Format('unterminated`,
+		`States.Format('a', )`,
+		`States.Format('a') extra`,
+		`States.Array(1 2)`,
+		`States.ArrayLength($.)`,
+	} {
+		_, err := parseIntrinsic(intrinsic)
+		assert.Error(t, err, intrinsic)
+	}
+}
+
+var passIntrinsicMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Pass",
+			"Parameters": {
+				"greeting.$": "States.Format('Hello, {}!', $.name)",
+				"id.$": "States.UUID()",
+				"random.$": "States.MathRandom(1, 10)"
+			},
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Intrinsic_Functions() {
+	sm, err := FromJSON(passIntrinsicMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestIntrinsicWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestIntrinsicWorkflow", map[string]interface{}{"name": "World"})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	err = s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+
+	s.Equal("Hello, World!", result["greeting"])
+	s.Len(result["id"], 36)
+	s.GreaterOrEqual(result["random"], float64(1))
+	s.Less(result["random"], float64(10))
+}
+
+var passSideEffectsMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Pass",
+			"Parameters": {
+				"a.$": "States.MathRandom(0, 1000000)",
+				"b.$": "States.MathRandom(0, 1000000)",
+				"c.$": "States.MathRandom(0, 1000000)",
+				"d.$": "States.MathRandom(0, 1000000)",
+				"e.$": "States.MathRandom(0, 1000000)",
+				"h.$": "States.MathRandom(0, 1000000)",
+				"i.$": "States.MathRandom(0, 1000000)",
+				"j.$": "States.MathRandom(0, 1000000)",
+				"k.$": "States.MathRandom(0, 1000000)",
+				"l.$": "States.MathRandom(0, 1000000)",
+				"nested": {
+					"f.$": "States.MathRandom(0, 1000000)",
+					"g.$": "States.MathRandom(0, 1000000)"
+				},
+				"z.$": "States.MathRandom(0, 1000000)"
+			},
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Intrinsic_Functions_Side_Effect_Order() {
+	sm, err := FromJSON(passSideEffectsMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	// Side effects are replayed in the order they were recorded, so keys must be resolved in sorted
+	// order, nested objects included, for the values to land on the same keys
+	const seed = 42
+	random := rand.New(rand.NewSource(seed))
+	next := func() float64 { return float64(random.Intn(1000000)) }
+
+	expected := map[string]interface{}{}
+	for _, key := range []string{"a", "b", "c", "d", "e", "h", "i", "j", "k", "l"} {
+		expected[key] = next()
+	}
+	expected["nested"] = map[string]interface{}{"f": next(), "g": next()}
+	expected["z"] = next()
+
+	RegisterWorkflow("TestIntrinsicSideEffectOrderWorkflow", *sm)
+
+	rand.Seed(seed)
+	s.env.ExecuteWorkflow("TestIntrinsicSideEffectOrderWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(expected, result)
+}
@@ -0,0 +1,63 @@
+package aslworkflow
+
+import (
+	"errors"
+	"sync"
+
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// Task states with LocalActivity run their Resource as a local activity in the workflow worker instead
+// of calling the TaskHandler, which saves the round trip through a task list for short pure functions.
+// The activity for the resource is registered with RegisterLocalActivity, or RegisterActivities.
+//
+//	aslworkflow.RegisterLocalActivity("example:activity:Normalize", NormalizeActivity)
+//
+//	"Normalize": {
+//		"Type": "Task",
+//		"Resource": "example:activity:Normalize",
+//		"LocalActivity": true,
+//		"TimeoutSeconds": 5,
+//		"RetryPolicy": {"InitialIntervalSeconds": 1, "MaximumAttempts": 3},
+//		"End": true
+//	}
+//
+// LocalActivity can also be set for resources with ResourceRegistry.SetActivityOptions. TimeoutSeconds
+// is the schedule to close timeout of the local activity and the RetryPolicy is applied to it, the
+// other ActivityOptions and HeartbeatSeconds do not apply to local activities.
+
+var ErrLocalActivityNotRegistered = errors.New("local activity has not been registered")
+
+var localActivities = struct {
+	sync.RWMutex
+	activities map[string]Activity
+}{activities: map[string]Activity{}}
+
+// RegisterLocalActivity sets the function run for the resource by Task states with LocalActivity
+func RegisterLocalActivity(resource string, activityFunc Activity) {
+	localActivities.Lock()
+	defer localActivities.Unlock()
+	localActivities.activities[resource] = activityFunc
+}
+
+func localActivity(resource string) (Activity, bool) {
+	localActivities.RLock()
+	defer localActivities.RUnlock()
+	activityFunc, ok := localActivities.activities[resource]
+	return activityFunc, ok
+}
+
+// withLocalActivityOptions sets the local activity options from the activity options of the state
+func withLocalActivityOptions(ctx workflow.Context, ao workflow.ActivityOptions) workflow.Context {
+	return workflow.WithLocalActivityOptions(ctx, workflow.LocalActivityOptions{
+		ScheduleToCloseTimeout: ao.StartToCloseTimeout,
+		RetryPolicy:            ao.RetryPolicy,
+	})
+}
+
+func executeLocalActivity(ctx workflow.Context, activityFunc Activity, input interface{}) (interface{}, error) {
+	var result interface{}
+	err := workflow.ExecuteLocalActivity(ctx, activityFunc, input).Get(ctx, &result)
+	return result, err
+}
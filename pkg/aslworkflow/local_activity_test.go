@@ -0,0 +1,100 @@
+package aslworkflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/cadence/workflow"
+)
+
+var localActivityMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "example:local:Normalize",
+			"LocalActivity": true,
+			"TimeoutSeconds": 5,
+			"RetryPolicy": {
+				"InitialIntervalSeconds": 1,
+				"MaximumAttempts": 3
+			},
+			"ResultPath": "$.normalized",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Task_Local_Activity() {
+	sm, err := FromJSON(localActivityMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	attempts := 0
+	RegisterLocalActivity("example:local:Normalize", func(ctx context.Context, input interface{}) (interface{}, error) {
+		attempts++
+		if attempts < 2 {
+			return nil, errors.New("flaky")
+		}
+		return input.(map[string]interface{})["name"], nil
+	})
+
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		return nil, errors.New("the handler must not be called")
+	}
+	RegisterHandler(handler)
+
+	RegisterWorkflow("TestTaskLocalActivityWorkflow", *sm)
+	s.env.ExecuteWorkflow("TestTaskLocalActivityWorkflow", map[string]interface{}{"name": "example"})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(2, attempts)
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal("example", result["normalized"])
+}
+
+func (s *UnitTestSuite) Test_Workflow_Task_Local_Activity_Not_Registered() {
+	sm, err := FromJSON(taskMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	registry := NewResourceRegistry()
+	registry.RegisterPrefix("arn:aws:", namedHandler("aws"))
+	registry.SetActivityOptions("arn:aws:resource:*", ActivityOptions{LocalActivity: to.Boolp(true)})
+
+	s.NoError(RegisterWorkflowWithRegistry("TestTaskLocalActivityNotRegisteredWorkflow", *sm, registry))
+	s.env.ExecuteWorkflow("TestTaskLocalActivityNotRegisteredWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	err = s.env.GetWorkflowError()
+	if s.Error(err) {
+		s.Contains(err.Error(), ErrLocalActivityNotRegistered.Error())
+	}
+}
+
+func TestTaskStateLocalActivityValidate(t *testing.T) {
+	_, err := FromJSON([]byte(`{
+		"StartAt": "Example1",
+		"States": {
+			"Example1": {
+				"Type": "Task",
+				"Resource": "http:invoke",
+				"LocalActivity": true,
+				"End": true
+			}
+		}
+	}`))
+	assert.EqualError(t, err, "invalid state machine:\nTaskState(Example1) Error: LocalActivity is not supported by Resource \"http:invoke\"")
+}
@@ -3,6 +3,9 @@ package aslworkflow
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"go.uber.org/cadence/workflow"
 )
@@ -13,15 +16,166 @@ type StateMachine struct {
 	Comment        string
 	Version        string
 	TimeoutSeconds int32
+
+	// registry routes Task resources, it is not part of the definition so it is not serialized
+	registry *ResourceRegistry
 }
 
 // States is the collection of states
 type States map[string]State
 
+// FromJSON parses and validates a state machine definition
 func FromJSON(raw []byte) (*StateMachine, error) {
 	var sm StateMachine
 	err := json.Unmarshal(raw, &sm)
-	return &sm, err
+	if err != nil {
+		return &sm, err
+	}
+
+	return &sm, sm.Validate()
+}
+
+// ValidationErrors is every problem found when validating a state machine
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid state machine:\n%v", strings.Join(messages, "\n"))
+}
+
+// Validate checks the whole machine, including every state and branch, and returns ValidationErrors
+// listing all of the problems found
+func (m *StateMachine) Validate() error {
+	return m.validate(m.registry)
+}
+
+// validate checks the machine as if it had the registry
+func (m *StateMachine) validate(registry *ResourceRegistry) error {
+	errs := validateStates(m.StartAt, m.States)
+	errs = append(errs, m.validateResources(registry)...)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStates(startAt string, states States) ValidationErrors {
+	var errs ValidationErrors
+
+	if startAt == "" {
+		errs = append(errs, fmt.Errorf("StartAt must be defined"))
+	} else if states[startAt] == nil {
+		errs = append(errs, fmt.Errorf("StartAt state %q does not exist", startAt))
+	}
+
+	// Sort names so errors are always reported in the same order
+	var names []string
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s := states[name]
+
+		if err := s.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+
+		// Transitions are only valid within the same scope, so branches cannot leave their Parallel or Map
+		for _, next := range transitions(s) {
+			if states[next] == nil {
+				errs = append(errs, fmt.Errorf("%v transition to %q does not exist", errorPrefix(s), next))
+			}
+		}
+
+		for i, branch := range branches(s) {
+			for _, err := range validateStates(branch.StartAt, branch.States) {
+				errs = append(errs, fmt.Errorf("%v Branch[%d] %w", errorPrefix(s), i, err))
+			}
+		}
+	}
+
+	// Every state must be reachable from StartAt
+	reached := map[string]bool{}
+	queue := []string{startAt}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		s := states[name]
+		if s == nil || reached[name] {
+			continue
+		}
+		reached[name] = true
+		queue = append(queue, transitions(s)...)
+	}
+
+	for _, name := range names {
+		if !reached[name] {
+			errs = append(errs, fmt.Errorf("%v is unreachable", errorPrefix(states[name])))
+		}
+	}
+
+	return errs
+}
+
+// transitions returns the names of every state that can be executed after s
+func transitions(s State) []string {
+	var next []*string
+
+	switch typeState := s.(type) {
+	case *TaskState:
+		next = append(next, typeState.Next)
+		next = append(next, catcherTransitions(typeState.Catch)...)
+	case *ParallelState:
+		next = append(next, typeState.Next)
+		next = append(next, catcherTransitions(typeState.Catch)...)
+	case *MapState:
+		next = append(next, typeState.Next)
+		next = append(next, catcherTransitions(typeState.Catch)...)
+	case *PassState:
+		next = append(next, typeState.Next)
+	case *WaitState:
+		next = append(next, typeState.Next, typeState.TimeoutNext)
+	case *ChoiceState:
+		next = append(next, typeState.Default)
+		for _, choice := range typeState.Choices {
+			next = append(next, choice.Next)
+		}
+	}
+
+	var names []string
+	for _, n := range next {
+		if n != nil {
+			names = append(names, *n)
+		}
+	}
+	return names
+}
+
+func catcherTransitions(catchers []*Catcher) []*string {
+	var next []*string
+	for _, catcher := range catchers {
+		next = append(next, catcher.Next)
+	}
+	return next
+}
+
+// branches returns the nested state machines of Parallel and Map states
+func branches(s State) []Branch {
+	switch typeState := s.(type) {
+	case *ParallelState:
+		return typeState.Branches
+	case *MapState:
+		if processor := typeState.processor(); processor != nil {
+			return []Branch{*processor}
+		}
+	}
+	return nil
 }
 
 func (sm *States) UnmarshalJSON(b []byte) error {
@@ -103,6 +257,10 @@ func unmarshallState(name string, rawJSON *json.RawMessage) ([]State, error) {
 		var s ParallelState
 		err = json.Unmarshal(*rawJSON, &s)
 		newState = &s
+	case "Map":
+		var s MapState
+		err = json.Unmarshal(*rawJSON, &s)
+		newState = &s
 	default:
 		err = fmt.Errorf("unknown state %q", stateType.Type)
 	}
@@ -120,6 +278,48 @@ func unmarshallState(name string, rawJSON *json.RawMessage) ([]State, error) {
 }
 
 func (m *StateMachine) Execute(ctx workflow.Context, input interface{}) (interface{}, error) {
+	if m.TimeoutSeconds <= 0 {
+		return m.execute(ctx, input)
+	}
+
+	// Run the machine alongside a timer, whichever finishes first wins
+	childCtx, cancelHandler := workflow.WithCancel(ctx)
+	timeout := time.Duration(m.TimeoutSeconds) * time.Second
+
+	var output interface{}
+	var err error
+	timedOut := false
+
+	selector := workflow.NewSelector(ctx)
+	selector.AddFuture(workflow.NewTimer(childCtx, timeout), func(f workflow.Future) {
+		if f.Get(ctx, nil) == nil {
+			timedOut = true
+		}
+	})
+	selector.AddFuture(executeMachineAsync(childCtx, m, input), func(f workflow.Future) {
+		err = f.Get(ctx, &output)
+	})
+	selector.Select(ctx)
+
+	// Stop the timer or the machine, whichever is still running
+	cancelHandler()
+
+	if timedOut {
+		return nil, newStatesError(StatesTimeout, fmt.Sprintf("state machine timed out after %v", timeout))
+	}
+
+	return output, err
+}
+
+func executeMachineAsync(ctx workflow.Context, m *StateMachine, input interface{}) workflow.Future {
+	future, settable := workflow.NewFuture(ctx)
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		settable.Set(m.execute(ctx, input))
+	})
+	return future
+}
+
+func (m *StateMachine) execute(ctx workflow.Context, input interface{}) (interface{}, error) {
 	nextState := &m.StartAt
 
 	for {
@@ -128,7 +328,7 @@ func (m *StateMachine) Execute(ctx workflow.Context, input interface{}) (interfa
 			return nil, fmt.Errorf("next state invalid (%v)", *nextState)
 		}
 
-		output, next, err := s.Execute(ctx, input)
+		output, next, err := s.Execute(withState(ctx, *nextState), input)
 
 		if err != nil {
 			return nil, err
@@ -154,6 +354,10 @@ func tasksFromStates(states States) []*TaskState {
 			for _, branch := range parallelState.Branches {
 				tasks = append(tasks, branch.Tasks()...)
 			}
+		case *MapState:
+			if processor := typeState.processor(); processor != nil {
+				tasks = append(tasks, processor.Tasks()...)
+			}
 		}
 	}
 	return tasks
@@ -172,16 +376,72 @@ func (m *StateMachine) RegisterWorkflow(name string) {
 // Keep track of registered activities so we don't register the same activity more than once
 var registeredActivities = map[string]bool{}
 
-func (m *StateMachine) RegisterActivities(activityFunc Activity) {
+// RegisterActivities registers the function as the activity for each Task resource, and as the local
+// activity for Tasks run with LocalActivity. The function is an Activity or a typed activity, see
+// TypedActivity. Resources that already have an activity are skipped. To register a different function
+// per resource use RegisterResourceActivities.
+func (m *StateMachine) RegisterActivities(activityFunc interface{}) error {
+	adapted, err := TypedActivity(activityFunc)
+	if err != nil {
+		return err
+	}
+
 	for _, task := range m.Tasks() {
-		resourceName := *task.Resource
+		resourceName, _ := parseTaskResource(*task.Resource)
 
-		// Check to see if this activity has already been registered, and skip if so
-		if registeredActivities[resourceName] {
+		// Check to see if this activity has already been registered, or is run by the interpreter, and skip if so
+		if registeredActivities[resourceName] || isBuiltinResource(resourceName) {
 			continue
 		}
 
-		RegisterActivity(resourceName, activityFunc)
+		RegisterActivity(resourceName, adapted)
+		RegisterLocalActivity(resourceName, adapted)
 		registeredActivities[resourceName] = true
 	}
+
+	return nil
+}
+
+// RegisterResourceActivities registers an activity for each resource in the map, the functions are Activity
+// or typed activities, see TypedActivity. Returns an error if a function has the wrong signature, or a
+// resource is not used by the machine or already has an activity, e.g. from RegisterActivities. Every
+// entry is checked before any is registered, so on error nothing is registered and the call can be retried.
+func (m *StateMachine) RegisterResourceActivities(activities map[string]interface{}) error {
+	used := map[string]bool{}
+	for _, task := range m.Tasks() {
+		resourceName, _ := parseTaskResource(*task.Resource)
+		used[resourceName] = true
+	}
+
+	// Sort so the first error is always the same
+	var resources []string
+	for resource := range activities {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	adapted := map[string]Activity{}
+	for _, resource := range resources {
+		if !used[resource] {
+			return fmt.Errorf("%v: resource is not used by the state machine", resource)
+		}
+
+		if registeredActivities[resource] {
+			return fmt.Errorf("%v: an activity is already registered for the resource", resource)
+		}
+
+		activityFunc, err := TypedActivity(activities[resource])
+		if err != nil {
+			return fmt.Errorf("%v: %w", resource, err)
+		}
+		adapted[resource] = activityFunc
+	}
+
+	for _, resource := range resources {
+		RegisterActivity(resource, adapted[resource])
+		RegisterLocalActivity(resource, adapted[resource])
+		registeredActivities[resource] = true
+	}
+
+	return nil
 }
@@ -33,3 +33,110 @@ func TestMachineGetTasks(t *testing.T) {
 	assert.Equal(t, 2, len(tasks))
 	assert.Equal(t, "arn:aws:resource:example", *tasks[0].Resource)
 }
+
+func TestMachineValidate(t *testing.T) {
+	_, err := FromJSON([]byte(`
+		{
+			"StartAt": "Missing",
+			"States": {
+				"Example1": {
+					"Type": "Task",
+					"Resource": "arn:aws:resource:example",
+					"Catch": [
+						{
+							"ErrorEquals": ["States.ALL"],
+							"Next": "MissingCatch"
+						}
+					],
+					"Next": "Exmaple2"
+				},
+				"Example2": {
+					"Type": "Parallel",
+					"Branches": [
+						{
+							"StartAt": "Branch1",
+							"States": {
+								"Branch1": {
+									"Type": "Pass",
+									"Next": "Example1"
+								}
+							}
+						}
+					],
+					"End": true
+				},
+				"Example3": {
+					"Type": "Choice",
+					"Choices": [
+						{
+							"Variable": "$.value",
+							"NumericEquals": 0,
+							"Next": "Example1"
+						}
+					],
+					"Default": "MissingDefault"
+				},
+				"Example4": {
+					"Type": "Pass"
+				}
+			}
+		}
+	`))
+
+	if assert.Error(t, err) {
+		errs, ok := err.(ValidationErrors)
+		if assert.True(t, ok) {
+			assert.Equal(t, []string{
+				`StartAt state "Missing" does not exist`,
+				`TaskState(Example1) Error: transition to "Exmaple2" does not exist`,
+				`TaskState(Example1) Error: transition to "MissingCatch" does not exist`,
+				`ParallelState(Example2) Error: Branch[0] PassState(Branch1) Error: transition to "Example1" does not exist`,
+				`ChoiceState(Example3) Error: transition to "MissingDefault" does not exist`,
+				`PassState(Example4) Error: End and Next both undefined`,
+				`TaskState(Example1) Error: is unreachable`,
+				`ParallelState(Example2) Error: is unreachable`,
+				`ChoiceState(Example3) Error: is unreachable`,
+				`PassState(Example4) Error: is unreachable`,
+			}, errorMessages(errs))
+		}
+	}
+}
+
+func TestMachineValidateMissingStartAt(t *testing.T) {
+	_, err := FromJSON([]byte(`
+		{
+			"States": {
+				"Example1": {
+					"Type": "Succeed"
+				}
+			}
+		}
+	`))
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "StartAt must be defined")
+	}
+}
+
+func errorMessages(errs []error) []string {
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+func TestMachineValidateParameters(t *testing.T) {
+	branch := `{"StartAt": "Branch1", "States": {"Branch1": {"Type": "Pass", "End": true}}}`
+
+	for name, state := range map[string]string{
+		"Task":             `{"Type": "Task", "Resource": "arn:aws:resource:example", "Parameters": {"id.$": "not a path"}, "End": true}`,
+		"Pass":             `{"Type": "Pass", "Parameters": {"nested": {"id.$": "States.Unknown($.id)"}}, "End": true}`,
+		"Parallel":         `{"Type": "Parallel", "Branches": [` + branch + `], "Parameters": {"id.$": 1}, "End": true}`,
+		"Map Parameters":   `{"Type": "Map", "ItemProcessor": ` + branch + `, "Parameters": {"id.$": "$.items["}, "End": true}`,
+		"Map ItemSelector": `{"Type": "Map", "ItemProcessor": ` + branch + `, "ItemSelector": {"id.$": "$.items["}, "End": true}`,
+	} {
+		_, err := FromJSON([]byte(`{"StartAt": "Example1", "States": {"Example1": ` + state + `}}`))
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,240 @@
+package aslworkflow
+
+import (
+	"fmt"
+
+	"github.com/checkr/states-language-cadence/pkg/jsonpath"
+	"github.com/coinbase/step/utils/to"
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// MapState runs the same set of states (the Iterator or ItemProcessor) for each element of an
+// array in the state input. Results are returned in the same order as the input items.
+//
+//	"Map": {
+//		"Type": "Map",
+//		"ItemsPath": "$.items",
+//		"MaxConcurrency": 2,
+//		"ItemProcessor": {
+//			"StartAt": "Process",
+//			"States": {
+//				"Process": {
+//					"Type": "Task",
+//					"Resource": "example:activity:Process",
+//					"End": true
+//				}
+//			}
+//		},
+//		"ResultPath": "$.results",
+//		"End": true
+//	}
+
+type MapState struct {
+	stateStr // Include Defaults
+
+	Type    *string
+	Comment *string `json:",omitempty"`
+
+	InputPath  *jsonpath.Path `json:",omitempty"`
+	OutputPath *jsonpath.Path `json:",omitempty"`
+	ResultPath *jsonpath.Path `json:",omitempty"`
+	ItemsPath  *jsonpath.Path `json:",omitempty"`
+
+	ResultSelector interface{} `json:",omitempty"`
+
+	ItemSelector interface{} `json:",omitempty"`
+	Parameters   interface{} `json:",omitempty"` // Legacy name for ItemSelector
+
+	Iterator      *Branch `json:",omitempty"`
+	ItemProcessor *Branch `json:",omitempty"` // New name for Iterator
+
+	MaxConcurrency int `json:",omitempty"` // 0 means no limit
+
+	Catch []*Catcher `json:",omitempty"`
+	Retry []*Retrier `json:",omitempty"`
+
+	Next *string `json:",omitempty"`
+	End  *bool   `json:",omitempty"`
+}
+
+func (s *MapState) process(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
+	itemsValue, err := getPath(ctx, s.ItemsPath, input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ItemsPath Error: %w", err)
+	}
+
+	items, ok := itemsValue.([]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("ItemsPath must select an array")
+	}
+
+	selector := s.itemSelector()
+	processor := s.processor()
+
+	resp, _, err := executeConcurrently(ctx, len(items), s.MaxConcurrency, func(ctx workflow.Context, i int) (interface{}, error) {
+		itemInput := items[i]
+		if selector != nil {
+			// $$.Map.Item is only available while building the item input
+			selected, err := replaceParamsJSONPath(withMapItem(ctx, i, items[i]), selector, input)
+			if err != nil {
+				return nil, err
+			}
+			itemInput = selected
+		}
+
+		output, _, err := processor.Execute(ctx, itemInput)
+		return output, err
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return interface{}(resp), nextState(s.Next, s.End), nil
+}
+
+func (s *MapState) Execute(ctx workflow.Context, input interface{}) (output interface{}, next *string, err error) {
+	return processError(s,
+		processCatcher(s.Catch,
+			processRetrier(s.Retry,
+				processInputOutput(
+					s.InputPath,
+					s.OutputPath,
+					processResult(s.ResultPath, processResultSelector(s.ResultSelector, s.process)),
+				),
+			),
+		),
+	)(ctx, input)
+}
+
+// processor returns the Branch run for each item, ItemProcessor takes precedence over Iterator
+func (s *MapState) processor() *Branch {
+	if s.ItemProcessor != nil {
+		return s.ItemProcessor
+	}
+	return s.Iterator
+}
+
+// itemSelector returns the template used to build each item input, ItemSelector takes precedence over Parameters
+func (s *MapState) itemSelector() interface{} {
+	if s.ItemSelector != nil {
+		return s.ItemSelector
+	}
+	return s.Parameters
+}
+
+// executeConcurrently runs fn for each index in [0, count) as workflow coroutines, never running more than
+// maxConcurrency at once (0 is unbounded). Results are returned in index order. If any call fails the
+// remaining calls are cancelled and the first error is returned with its index and the results of the
+// calls that had already finished.
+func executeConcurrently(ctx workflow.Context, count int, maxConcurrency int, fn func(workflow.Context, int) (interface{}, error)) ([]interface{}, int, error) {
+	workers := count
+	if maxConcurrency > 0 && maxConcurrency < count {
+		workers = maxConcurrency
+	}
+
+	childCtx, cancelHandler := workflow.WithCancel(ctx)
+	wg := workflow.NewWaitGroup(ctx)
+
+	resp := make([]interface{}, count)
+	var firstErr error
+	failed := -1
+	nextIndex := 0
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		workflow.Go(childCtx, func(ctx workflow.Context) {
+			defer wg.Done()
+
+			// Coroutines are run one at a time, so sharing nextIndex is safe
+			for firstErr == nil && nextIndex < count {
+				i := nextIndex
+				nextIndex++
+
+				output, err := fn(ctx, i)
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						failed = i
+						// cancel all pending iterations
+						cancelHandler()
+					}
+					return
+				}
+				resp[i] = output
+			}
+		})
+	}
+
+	wg.Wait(ctx)
+
+	if firstErr != nil {
+		return resp, failed, firstErr
+	}
+
+	return resp, -1, nil
+}
+
+func (s *MapState) Validate() error {
+	s.SetType(to.Strp("Map"))
+
+	if err := ValidateNameAndType(s); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	// Next xor End
+	if err := isEndValid(s.Next, s.End); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if (s.Iterator == nil) == (s.ItemProcessor == nil) {
+		return fmt.Errorf("%v Exactly One (Iterator,ItemProcessor)", errorPrefix(s))
+	}
+
+	if s.ItemSelector != nil && s.Parameters != nil {
+		return fmt.Errorf("%v Only One (ItemSelector,Parameters)", errorPrefix(s))
+	}
+
+	if s.MaxConcurrency < 0 {
+		return fmt.Errorf("%v MaxConcurrency must be positive", errorPrefix(s))
+	}
+
+	if err := isResultPathValid("ResultPath", s.ResultPath); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isTemplateValid("ItemSelector", s.ItemSelector); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isTemplateValid("Parameters", s.Parameters); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isReferencePathValid("ItemsPath", s.ItemsPath); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isResultSelectorValid(s.ResultSelector); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isCatchValid(s.Catch); err != nil {
+		return err
+	}
+
+	if err := isRetryValid(s.Retry); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *MapState) SetType(t *string) {
+	s.Type = t
+}
+
+func (s *MapState) GetType() *string {
+	return s.Type
+}
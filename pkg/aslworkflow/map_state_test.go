@@ -0,0 +1,330 @@
+package aslworkflow
+
+import (
+	"time"
+
+	"go.uber.org/cadence/workflow"
+)
+
+var mapMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Map",
+			"ItemsPath": "$.items",
+			"MaxConcurrency": 2,
+			"ResultPath": "$.results",
+			"ItemProcessor": {
+				"StartAt": "Double",
+				"States": {
+					"Double": {
+						"Type": "Task",
+						"Resource": "example:double",
+						"End": true
+					}
+				}
+			},
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Map_State() {
+	workflowName := "TestMapWorkflow"
+
+	sm, err := FromJSON(mapMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	running := 0
+	maxRunning := 0
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		defer func() { running-- }()
+
+		// Later items finish first so completion order differs from input order
+		n := input.(map[string]interface{})["n"].(float64)
+		if err := workflow.Sleep(ctx, time.Duration(10-n)*time.Second); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"n": n * 2}, nil
+	}
+	RegisterHandler(handler)
+	defer DeregisterHandler()
+
+	var items []interface{}
+	for n := 1; n <= 5; n++ {
+		items = append(items, map[string]interface{}{"n": n})
+	}
+	exampleInput := map[string]interface{}{"items": items}
+
+	RegisterWorkflow(workflowName, *sm)
+	s.env.ExecuteWorkflow(workflowName, exampleInput)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	err = s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+
+	var doubled []float64
+	for _, r := range result["results"].([]interface{}) {
+		doubled = append(doubled, r.(map[string]interface{})["n"].(float64))
+	}
+	s.Equal([]float64{2, 4, 6, 8, 10}, doubled)
+	s.Equal(2, maxRunning)
+}
+
+func (s *UnitTestSuite) Test_Workflow_Map_State_Empty() {
+	workflowName := "TestMapEmptyWorkflow"
+
+	sm, err := FromJSON(mapMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	exampleInput := map[string]interface{}{"items": []interface{}{}}
+
+	RegisterWorkflow(workflowName, *sm)
+	s.env.ExecuteWorkflow(workflowName, exampleInput)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	err = s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+
+	s.Equal([]interface{}{}, result["results"])
+}
+
+var mapItemSelectorMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Map",
+			"ItemsPath": "$.items",
+			"ItemSelector": {
+				"name.$": "$$.Map.Item.Value.name",
+				"index.$": "$$.Map.Item.Index",
+				"owner.$": "$.owner"
+			},
+			"ItemProcessor": {
+				"StartAt": "Item",
+				"States": {
+					"Item": {
+						"Type": "Pass",
+						"End": true
+					}
+				}
+			},
+			"ResultPath": "$.results",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Map_State_Item_Selector() {
+	sm, err := FromJSON(mapItemSelectorMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestMapItemSelectorWorkflow", *sm)
+	s.env.ExecuteWorkflow("TestMapItemSelectorWorkflow", map[string]interface{}{
+		"owner": "example",
+		"items": []interface{}{
+			map[string]interface{}{"name": "a", "ignored": true},
+			map[string]interface{}{"name": "b"},
+		},
+	})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	// Each item input is built from the item and the Map's input
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal([]interface{}{
+		map[string]interface{}{"name": "a", "index": 0.0, "owner": "example"},
+		map[string]interface{}{"name": "b", "index": 1.0, "owner": "example"},
+	}, result["results"])
+}
+
+var mapRetryCatchMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Map",
+			"ItemsPath": "$.items",
+			"ItemProcessor": {
+				"StartAt": "Item",
+				"States": {
+					"Item": {
+						"Type": "Task",
+						"Resource": "example:item",
+						"End": true
+					}
+				}
+			},
+			"Retry": [
+				{
+					"ErrorEquals": ["Item.Flaky"],
+					"MaxAttempts": 1
+				}
+			],
+			"Catch": [
+				{
+					"ErrorEquals": ["States.ALL"],
+					"ResultPath": "$.error",
+					"Next": "Recovered"
+				}
+			],
+			"ResultPath": "$.results",
+			"End": true
+		},
+		"Recovered": {
+			"Type": "Pass",
+			"End": true
+		}
+	}
+}
+`)
+
+// mapItemHandler fails items named flaky on their first call and items named bad on every call
+func mapItemHandler(calls map[string]int) TaskHandler {
+	return func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		name := input.(map[string]interface{})["name"].(string)
+		calls[name]++
+
+		switch {
+		case name == "flaky" && calls[name] == 1:
+			return nil, NewError("Item.Flaky", "try again", nil)
+		case name == "bad":
+			return nil, NewError("Item.Failed", "bad item", nil)
+		}
+		return map[string]interface{}{"name": name, "done": true}, nil
+	}
+}
+
+func (s *UnitTestSuite) Test_Workflow_Map_State_Retry() {
+	sm, err := FromJSON(mapRetryCatchMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	calls := map[string]int{}
+	RegisterHandler(mapItemHandler(calls))
+	defer DeregisterHandler()
+
+	RegisterWorkflow("TestMapRetryWorkflow", *sm)
+	s.env.ExecuteWorkflow("TestMapRetryWorkflow", map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"name": "flaky"},
+			map[string]interface{}{"name": "good"},
+		},
+	})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	// The first failure stops the Map before good runs, the retry runs every item again
+	s.Equal(2, calls["flaky"])
+	s.Equal(1, calls["good"])
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal([]interface{}{
+		map[string]interface{}{"name": "flaky", "done": true},
+		map[string]interface{}{"name": "good", "done": true},
+	}, result["results"])
+}
+
+func (s *UnitTestSuite) Test_Workflow_Map_State_Catch() {
+	sm, err := FromJSON(mapRetryCatchMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	calls := map[string]int{}
+	RegisterHandler(mapItemHandler(calls))
+	defer DeregisterHandler()
+
+	RegisterWorkflow("TestMapCatchWorkflow", *sm)
+	s.env.ExecuteWorkflow("TestMapCatchWorkflow", map[string]interface{}{
+		"items": []interface{}{map[string]interface{}{"name": "bad"}},
+	})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	// Item.Failed is not retried
+	s.Equal(1, calls["bad"])
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{"Error": "Item.Failed", "Cause": "bad item"}, result["error"])
+	s.NotContains(result, "results")
+}
+
+func (s *UnitTestSuite) Test_Workflow_Map_State_Failure_Cancels_Items() {
+	sm, err := FromJSON(mapMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	canceled := 0
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		n := input.(map[string]interface{})["n"].(float64)
+		if n == 1 {
+			if err := workflow.Sleep(ctx, time.Second); err != nil {
+				return nil, err
+			}
+			return nil, NewError("Item.Failed", "first item failed", nil)
+		}
+
+		if err := workflow.Sleep(ctx, time.Hour); err != nil {
+			canceled++
+			return nil, err
+		}
+		return input, nil
+	}
+	RegisterHandler(handler)
+	defer DeregisterHandler()
+
+	var items []interface{}
+	for n := 1; n <= 4; n++ {
+		items = append(items, map[string]interface{}{"n": n})
+	}
+
+	startTime := s.env.Now()
+	RegisterWorkflow("TestMapFailureCancelsItemsWorkflow", *sm)
+	s.env.ExecuteWorkflow("TestMapFailureCancelsItemsWorkflow", map[string]interface{}{"items": items})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.Equal(time.Second, s.env.Now().Sub(startTime))
+
+	// MaxConcurrency is 2, so only item 2 was running, items 3 and 4 never start
+	s.Equal(1, canceled)
+
+	err = s.env.GetWorkflowError()
+	if s.Error(err) {
+		s.Equal("Item.Failed", ErrorName(err))
+	}
+}
@@ -0,0 +1,165 @@
+package aslworkflow
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// Task states with an asl:statemachine: Resource run another state machine registered with
+// RegisterStateMachine inline, in the same workflow, so common sub-flows can be shared between
+// definitions. The input is the state's effective input after Parameters, and the output of the
+// nested machine is the result, so ResultSelector and ResultPath apply to it as usual.
+//
+//	aslworkflow.RegisterStateMachine("NotifyAndAwaitAck", *notifyMachine)
+//
+//	"Notify": {
+//		"Type": "Task",
+//		"Resource": "asl:statemachine:NotifyAndAwaitAck",
+//		"Parameters": {"recipient.$": "$.owner"},
+//		"ResultPath": "$.ack",
+//		"Next": "Done"
+//	}
+//
+// Errors raised by the nested machine, e.g. by a Fail state, keep their name so the Task can Retry or
+// Catch them. With ChildWorkflowOptions the machine is run as a child workflow instead, waiting for its
+// result like a cadence:workflow:<Name>.sync resource.
+//
+// RegisterStateMachine rejects machines that would run themselves through asl:statemachine: resources.
+
+const StateMachineResourcePrefix = "asl:statemachine:"
+
+var ErrStateMachineNotRegistered = errors.New("state machine has not been registered")
+
+var stateMachines = struct {
+	sync.RWMutex
+	machines map[string]*StateMachine
+}{machines: map[string]*StateMachine{}}
+
+// RegisterStateMachine makes the machine available to asl:statemachine:<name> resources, and registers
+// it as the workflow asl:statemachine:<name> for Tasks that run it as a child workflow
+func RegisterStateMachine(name string, sm StateMachine) error {
+	if name == "" {
+		return fmt.Errorf("state machine name must not be empty")
+	}
+
+	stateMachines.Lock()
+	defer stateMachines.Unlock()
+
+	if _, ok := stateMachines.machines[name]; ok {
+		return fmt.Errorf("state machine %q is already registered", name)
+	}
+
+	stateMachines.machines[name] = &sm
+	if cycle := findStateMachineCycle(name, stateMachines.machines); cycle != nil {
+		delete(stateMachines.machines, name)
+		return fmt.Errorf("state machine %q runs itself: %v", name, strings.Join(cycle, " -> "))
+	}
+
+	RegisterWorkflow(StateMachineResourcePrefix+name, sm)
+	return nil
+}
+
+func registeredStateMachine(name string) (*StateMachine, bool) {
+	stateMachines.RLock()
+	defer stateMachines.RUnlock()
+	sm, ok := stateMachines.machines[name]
+	return sm, ok
+}
+
+// nestedStateMachines returns the names of the machines run by the Task states of a machine
+func nestedStateMachines(sm *StateMachine) []string {
+	var names []string
+	for _, task := range sm.Tasks() {
+		if task.Resource == nil {
+			continue
+		}
+
+		resource, _ := parseTaskResource(*task.Resource)
+		if strings.HasPrefix(resource, StateMachineResourcePrefix) {
+			names = append(names, strings.TrimPrefix(resource, StateMachineResourcePrefix))
+		}
+	}
+
+	// Tasks are collected from a map, so sort for a stable path in errors
+	sort.Strings(names)
+	return names
+}
+
+// findStateMachineCycle returns the path from the named machine back to itself, nil if there is none.
+// Machines that are not registered yet are skipped, they are checked when they are registered.
+func findStateMachineCycle(name string, machines map[string]*StateMachine) []string {
+	visited := map[string]bool{}
+
+	var visit func(path []string) []string
+	visit = func(path []string) []string {
+		current := path[len(path)-1]
+		sm, ok := machines[current]
+		if !ok {
+			return nil
+		}
+
+		for _, next := range nestedStateMachines(sm) {
+			if next == name {
+				return append(path, next)
+			}
+
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+
+			if cycle := visit(append(path[:len(path):len(path)], next)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+
+	return visit([]string{name})
+}
+
+func executeStateMachine(ctx workflow.Context, s *TaskState, resource string, input interface{}) (interface{}, error) {
+	if s.ChildWorkflowOptions != nil {
+		return executeChildWorkflow(ctx, s, ChildWorkflowResourcePrefix+resource+childWorkflowSyncSuffix, input)
+	}
+
+	name := strings.TrimPrefix(resource, StateMachineResourcePrefix)
+
+	sm, ok := registeredStateMachine(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrStateMachineNotRegistered, name)
+	}
+
+	// Guards against cycles through machines registered after the check in RegisterStateMachine
+	stack := getStateMachineStack(ctx)
+	for _, running := range stack {
+		if running == name {
+			return nil, fmt.Errorf("state machine %q runs itself: %v -> %v", name, strings.Join(stack, " -> "), name)
+		}
+	}
+	ctx = withStateMachineStack(ctx, append(stack[:len(stack):len(stack)], name))
+
+	if sm.registry != nil {
+		ctx = withRegistry(ctx, sm.registry)
+	}
+
+	return sm.Execute(ctx, input)
+}
+
+const stateMachineStackContextKey contextKey = "aslStateMachineStack"
+
+func withStateMachineStack(ctx workflow.Context, stack []string) workflow.Context {
+	return workflow.WithValue(ctx, stateMachineStackContextKey, stack)
+}
+
+// getStateMachineStack returns the names of the nested machines being run, outermost first
+func getStateMachineStack(ctx workflow.Context) []string {
+	stack, _ := ctx.Value(stateMachineStackContextKey).([]string)
+	return stack
+}
@@ -0,0 +1,145 @@
+package aslworkflow
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var nestedMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Pass",
+			"Parameters": {
+				"greeting.$": "States.Format('hello {}', $.name)"
+			},
+			"End": true
+		}
+	}
+}
+`)
+
+var nestedFailMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Fail",
+			"Error": "Nested.Rejected",
+			"Cause": "the nested machine failed"
+		}
+	}
+}
+`)
+
+var nestingMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "asl:statemachine:TestNested",
+			"Parameters": {"name.$": "$.user"},
+			"ResultPath": "$.inline",
+			"Next": "Example2"
+		},
+		"Example2": {
+			"Type": "Task",
+			"Resource": "asl:statemachine:TestNested",
+			"ChildWorkflowOptions": {},
+			"ResultPath": "$.child",
+			"Next": "Example3"
+		},
+		"Example3": {
+			"Type": "Task",
+			"Resource": "asl:statemachine:TestNestedFail",
+			"Catch": [
+				{
+					"ErrorEquals": ["Nested.Rejected"],
+					"ResultPath": "$.error",
+					"Next": "Example4"
+				}
+			],
+			"End": true
+		},
+		"Example4": {
+			"Type": "Succeed"
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Nested_State_Machine() {
+	for name, raw := range map[string][]byte{
+		"TestNested":     nestedMachine,
+		"TestNestedFail": nestedFailMachine,
+	} {
+		sm, err := FromJSON(raw)
+		if err != nil {
+			s.NoError(err)
+			return
+		}
+		s.NoError(RegisterStateMachine(name, *sm))
+	}
+
+	sm, err := FromJSON(nestingMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestNestingWorkflow", *sm)
+	s.env.ExecuteWorkflow("TestNestingWorkflow", map[string]interface{}{"user": "inline"})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{"greeting": "hello inline"}, result["inline"])
+	s.Equal(map[string]interface{}{"greeting": "hello inline"}, result["child"])
+	s.Equal("Nested.Rejected", result["error"].(map[string]interface{})["Error"])
+}
+
+// nestingMachineJSON returns a machine that runs the resources in order
+func nestingMachineJSON(resources ...string) []byte {
+	states := ""
+	for i, resource := range resources {
+		states += fmt.Sprintf(`"Example%d": {"Type": "Task", "Resource": %q, "Next": "Example%d"},`, i, resource, i+1)
+	}
+	return []byte(fmt.Sprintf(`{"StartAt": "Example0", "States": {%v "Example%d": {"Type": "Succeed"}}}`, states, len(resources)))
+}
+
+func TestRegisterStateMachineCycle(t *testing.T) {
+	machines := map[string][]byte{
+		"TestCycleA": nestingMachineJSON("asl:statemachine:TestCycleB"),
+		"TestCycleB": nestingMachineJSON("asl:statemachine:TestCycleC", "asl:statemachine:TestCycleUnregistered"),
+		"TestCycleC": nestingMachineJSON("asl:statemachine:TestCycleA"),
+	}
+
+	for _, name := range []string{"TestCycleA", "TestCycleB"} {
+		sm, err := FromJSON(machines[name])
+		assert.NoError(t, err)
+		assert.NoError(t, RegisterStateMachine(name, *sm))
+	}
+
+	sm, err := FromJSON(machines["TestCycleC"])
+	assert.NoError(t, err)
+
+	err = RegisterStateMachine("TestCycleC", *sm)
+	assert.EqualError(t, err, `state machine "TestCycleC" runs itself: TestCycleC -> TestCycleA -> TestCycleB -> TestCycleC`)
+
+	_, ok := registeredStateMachine("TestCycleC")
+	assert.False(t, ok)
+
+	err = RegisterStateMachine("TestCycleA", *sm)
+	assert.EqualError(t, err, `state machine "TestCycleA" is already registered`)
+}
+
+func TestTaskStateStateMachineValidate(t *testing.T) {
+	_, err := FromJSON(nestingMachineJSON("asl:statemachine:"))
+	assert.EqualError(t, err, "invalid state machine:\nTaskState(Example0) Error: Resource must name a state machine")
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/checkr/states-language-cadence/pkg/jsonpath"
 	"github.com/coinbase/step/utils/to"
+	"go.uber.org/cadence"
 	"go.uber.org/cadence/workflow"
 )
 
@@ -18,6 +19,10 @@ type ParallelState struct {
 	ResultPath *jsonpath.Path `json:",omitempty"`
 	Parameters interface{}    `json:",omitempty"`
 
+	ResultSelector interface{} `json:",omitempty"`
+
+	MaxConcurrency int `json:",omitempty"` // 0 means no limit
+
 	Catch []*Catcher `json:",omitempty"`
 	Retry []*Retrier `json:",omitempty"`
 
@@ -31,50 +36,44 @@ type Branch struct {
 }
 
 func (s *ParallelState) process(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
-	// You can use the context passed in to activity as a way to cancel the activity like standard GO way.
-	// Cancelling a parent context will cancel all the derived contexts as well.
-	// In the parallel block, we want to execute all of them in parallel and wait for all of them.
-	// if one activity fails then we want to cancel all the rest of them as well.
-	childCtx, cancelHandler := workflow.WithCancel(ctx)
-	selector := workflow.NewSelector(ctx)
-	var activityErr error
-
-	var resp []interface{}
-
-	for _, branch := range s.Branches {
-		f := executeAsync(s, branch, childCtx, input)
-		selector.AddFuture(f, func(f workflow.Future) {
-			var r interface{}
-			err := f.Get(ctx, &r)
-			if err != nil {
-				// cancel all pending activities
-				cancelHandler()
-				activityErr = err
-			}
-			resp = append(resp, r)
-		})
-	}
+	// Branches run as coroutines, if one fails the others are cancelled. Results are kept in the
+	// order of Branches rather than the order they finish in.
+	resp, failed, err := executeConcurrently(ctx, len(s.Branches), s.MaxConcurrency, func(ctx workflow.Context, i int) (interface{}, error) {
+		output, _, err := s.Branches[i].Execute(ctx, input)
+		return output, err
+	})
 
-	for i := 0; i < len(s.Branches); i++ {
-		selector.Select(ctx) // this will wait for one branch
-		if activityErr != nil {
-			return nil, nil, activityErr
-		}
+	if err != nil {
+		return nil, nil, newBranchFailedError(failed, err, resp)
 	}
 
 	return interface{}(resp), nextState(s.Next, s.End), nil
 }
 
+// newBranchFailedError returns a States.BranchFailed error whose details also record the index and error
+// of the failed branch and the outputs of the branches that had finished, nil for the others
+func newBranchFailedError(branch int, err error, outputs []interface{}) error {
+	name := ErrorName(err)
+	cause := fmt.Sprintf("Branch[%d] failed with %v: %v", branch, name, ErrorCause(err))
+
+	details := errorOutput(to.Strp(StatesBranchFailed), &cause)
+	details["Branch"] = branch
+	details["BranchError"] = errorOutputFromError(err)
+	details["BranchOutputs"] = outputs
+
+	return cadence.NewCustomError(StatesBranchFailed, details)
+}
+
 func (s *ParallelState) Execute(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
 	return processError(s,
 		processCatcher(s.Catch,
-			processRetrier(s.Name(), s.Retry,
+			processRetrier(s.Retry,
 				processInputOutput(
 					s.InputPath,
 					s.OutputPath,
 					processParams(
 						s.Parameters,
-						processResult(s.ResultPath, s.process),
+						processResult(s.ResultPath, processResultSelector(s.ResultSelector, s.process)),
 					),
 				),
 			),
@@ -82,16 +81,7 @@ func (s *ParallelState) Execute(ctx workflow.Context, input interface{}) (interf
 	)(ctx, input)
 }
 
-func executeAsync(p *ParallelState, b Branch, ctx workflow.Context, input interface{}) workflow.Future {
-	future, settable := workflow.NewFuture(ctx)
-	workflow.Go(ctx, func(ctx workflow.Context) {
-		output, _, err := b.Execute(ctx, *p, input)
-		settable.Set(output, err)
-	})
-	return future
-}
-
-func (m *Branch) Execute(ctx workflow.Context, s ParallelState, input interface{}) (interface{}, *string, error) {
+func (m *Branch) Execute(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
 	nextState := &m.StartAt
 
 	for {
@@ -100,7 +90,7 @@ func (m *Branch) Execute(ctx workflow.Context, s ParallelState, input interface{
 			return nil, nil, fmt.Errorf("next state invalid (%v)", *nextState)
 		}
 
-		output, next, err := s.Execute(ctx, input)
+		output, next, err := s.Execute(withState(ctx, *nextState), input)
 
 		if err != nil {
 			return nil, nil, err
@@ -128,5 +118,38 @@ func (s *ParallelState) Validate() error {
 		return fmt.Errorf("%v %v", errorPrefix(s), err)
 	}
 
+	// Next xor End
+	if err := isEndValid(s.Next, s.End); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if len(s.Branches) == 0 {
+		return fmt.Errorf("%v must have Branches", errorPrefix(s))
+	}
+
+	if s.MaxConcurrency < 0 {
+		return fmt.Errorf("%v MaxConcurrency must be positive", errorPrefix(s))
+	}
+
+	if err := isResultPathValid("ResultPath", s.ResultPath); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isTemplateValid("Parameters", s.Parameters); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isResultSelectorValid(s.ResultSelector); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isCatchValid(s.Catch); err != nil {
+		return err
+	}
+
+	if err := isRetryValid(s.Retry); err != nil {
+		return err
+	}
+
 	return nil
 }
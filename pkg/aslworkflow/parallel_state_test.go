@@ -1,5 +1,11 @@
 package aslworkflow
 
+import (
+	"time"
+
+	"go.uber.org/cadence"
+)
+
 var parallelMachine = []byte(`
 {
 	"StartAt": "Example1",
@@ -62,3 +68,135 @@ func (s *UnitTestSuite) Test_Workflow_Parallel_State() {
 	s.True(result[0]["branch1"])
 	s.True(result[1]["branch2"])
 }
+
+var parallelOrderMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Parallel",
+			"MaxConcurrency": 1,
+			"End": true,
+			"Branches": [
+				{
+					"StartAt": "Slow",
+					"States": {
+						"Slow": {
+							"Type": "Wait",
+							"Seconds": 10,
+							"Next": "SlowResult"
+						},
+						"SlowResult": {
+							"Type": "Pass",
+							"Result": {"branch": "slow"},
+							"End": true
+						}
+					}
+				},
+				{
+					"StartAt": "Fast",
+					"States": {
+						"Fast": {
+							"Type": "Wait",
+							"Seconds": 1,
+							"Next": "FastResult"
+						},
+						"FastResult": {
+							"Type": "Pass",
+							"Result": {"branch": "fast"},
+							"End": true
+						}
+					}
+				}
+			]
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Parallel_State_Order() {
+	sm, err := FromJSON(parallelOrderMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestParallelOrderWorkflow", *sm)
+
+	startTime := s.env.Now()
+	s.env.ExecuteWorkflow("TestParallelOrderWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	// With MaxConcurrency 1 the branches run one after the other
+	s.Equal(11*time.Second, s.env.Now().Sub(startTime))
+
+	var result []interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal([]interface{}{
+		map[string]interface{}{"branch": "slow"},
+		map[string]interface{}{"branch": "fast"},
+	}, result)
+}
+
+var parallelFailedMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Parallel",
+			"End": true,
+			"Branches": [
+				{
+					"StartAt": "Branch1",
+					"States": {
+						"Branch1": {
+							"Type": "Pass",
+							"Result": {"branch": "done"},
+							"End": true
+						}
+					}
+				},
+				{
+					"StartAt": "Branch2",
+					"States": {
+						"Branch2": {
+							"Type": "Fail",
+							"Error": "ExampleError",
+							"Cause": "This is an example error"
+						}
+					}
+				}
+			]
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Parallel_State_Branch_Failed() {
+	sm, err := FromJSON(parallelFailedMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestParallelFailedWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestParallelFailedWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	customErr, ok := s.env.GetWorkflowError().(*cadence.CustomError)
+	if s.True(ok) {
+		s.Equal(StatesBranchFailed, customErr.Reason())
+
+		var details map[string]interface{}
+		s.NoError(customErr.Details(&details))
+		s.Equal(StatesBranchFailed, details["Error"])
+		s.Equal("Branch[1] failed with ExampleError: This is an example error", details["Cause"])
+		s.Equal(1.0, details["Branch"])
+		s.Equal(map[string]interface{}{"Error": "ExampleError", "Cause": "This is an example error"}, details["BranchError"])
+		s.Equal([]interface{}{map[string]interface{}{"branch": "done"}, nil}, details["BranchOutputs"])
+	}
+}
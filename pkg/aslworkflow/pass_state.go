@@ -58,6 +58,14 @@ func (s *PassState) Validate() error {
 		return fmt.Errorf("%v %v", errorPrefix(s), err)
 	}
 
+	if err := isResultPathValid("ResultPath", s.ResultPath); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isTemplateValid("Parameters", s.Parameters); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
 	return nil
 }
 
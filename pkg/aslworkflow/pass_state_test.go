@@ -1,5 +1,11 @@
 package aslworkflow
 
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
 var passMachine = []byte(`
 {
 	"StartAt": "Example1",
@@ -41,3 +47,64 @@ func (s *UnitTestSuite) Test_Workflow_Pass_State() {
 
 	s.Equal("example_output", result["test"])
 }
+
+var passQueryMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Pass",
+			"InputPath": "$.items[?(@.price > 10)]",
+			"Parameters": {
+				"names.$": "$[*].name"
+			},
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Pass_State_Query_Paths() {
+	sm, err := FromJSON(passQueryMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	exampleInput := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"name": "a", "price": 5},
+			map[string]interface{}{"name": "b", "price": 15},
+			map[string]interface{}{"name": "c", "price": 25},
+		},
+	}
+
+	RegisterWorkflow("TestPassQueryWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestPassQueryWorkflow", exampleInput)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	err = s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+
+	s.Equal([]interface{}{"b", "c"}, result["names"])
+}
+
+func TestPassStateResultPathValidate(t *testing.T) {
+	_, err := FromJSON([]byte(`
+		{
+			"StartAt": "Example1",
+			"States": {
+				"Example1": {
+					"Type": "Pass",
+					"ResultPath": "$.items[*]",
+					"End": true
+				}
+			}
+		}
+	`))
+	assert.Error(t, err)
+}
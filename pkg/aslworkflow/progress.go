@@ -0,0 +1,217 @@
+package aslworkflow
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/cadence/activity"
+	"go.uber.org/cadence/client"
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// HeartbeatActivity wraps an Activity so it heartbeats on its own, at half of the HeartbeatSeconds of the
+// Task state, and keeps the latest progress recorded with RecordProgress in the heartbeat details.
+//
+//	func Import(ctx context.Context, input interface{}) (interface{}, error) {
+//		var offset int
+//		if _, err := aslworkflow.GetProgress(ctx, &offset); err != nil {
+//			return nil, err
+//		}
+//
+//		for ; offset < total; offset++ {
+//			importRecord(offset)
+//			aslworkflow.RecordProgress(ctx, offset)
+//		}
+//		return nil, nil
+//	}
+//
+//	sm.RegisterActivities(aslworkflow.HeartbeatActivity(Import, aslworkflow.HeartbeatOptions{}))
+//
+// Cadence only hands heartbeat details to the next attempt of the same activity, so GetProgress resumes
+// attempts made by the Task's RetryPolicy. A Retry of the state schedules a new activity, which always
+// starts without progress.
+//
+// Heartbeat details are only visible to the activity. With a Client in the HeartbeatOptions the progress
+// is also signalled to the workflow, where the ProgressQuery returns it:
+//
+//	cadence --do <domain> workflow query --workflow_id <id> --query_type aslProgress
+//
+// Every report is a signal, one event in the workflow history and a decision task, so reports are
+// only sent when the progress changed and at most once per ReportInterval, one minute by default. Even
+// so an activity running for a day adds over a thousand events, keep the interval well above the
+// heartbeat for long running activities.
+
+// ProgressQuery returns the latest progress of each activity that reported any, as []TaskProgress
+const ProgressQuery = "aslProgress"
+
+// ProgressSignal is the signal HeartbeatActivity reports progress to the workflow with
+const ProgressSignal = "aslProgress"
+
+// TaskProgress is the progress reported by an activity
+type TaskProgress struct {
+	ActivityID string
+	Resource   string
+	Attempt    int32
+	Progress   interface{}
+}
+
+type progressContextKey struct{}
+
+// progressTracker holds the latest progress of an activity for the automatic heartbeats
+type progressTracker struct {
+	sync.Mutex
+	progress interface{}
+	set      bool
+	changed  bool
+}
+
+func (t *progressTracker) record(progress interface{}) {
+	t.Lock()
+	defer t.Unlock()
+	t.progress = progress
+	t.set = true
+	t.changed = true
+}
+
+// latest returns the progress, and whether it changed since it was last reported to the workflow
+func (t *progressTracker) latest() (progress interface{}, changed bool) {
+	t.Lock()
+	defer t.Unlock()
+	changed = t.changed
+	t.changed = false
+	return t.progress, changed
+}
+
+// DefaultProgressReportInterval is the ReportInterval used when none is set
+const DefaultProgressReportInterval = time.Minute
+
+// HeartbeatOptions configures HeartbeatActivity
+type HeartbeatOptions struct {
+	// Client reports the progress to the workflow, nil only heartbeats
+	Client client.Client
+
+	// ReportInterval is the minimum time between two reports to the workflow
+	ReportInterval time.Duration
+}
+
+// HeartbeatActivity returns the activity wrapped to heartbeat automatically, see RecordProgress
+func HeartbeatActivity(activityFunc Activity, options HeartbeatOptions) Activity {
+	if options.ReportInterval <= 0 {
+		options.ReportInterval = DefaultProgressReportInterval
+	}
+
+	return func(ctx context.Context, input interface{}) (interface{}, error) {
+		tracker := &progressTracker{}
+
+		// Carry the progress of the previous attempt until the activity records its own
+		if activity.HasHeartbeatDetails(ctx) {
+			var previous interface{}
+			if err := activity.GetHeartbeatDetails(ctx, &previous); err == nil {
+				tracker.progress, tracker.set = previous, true
+			}
+		}
+
+		ctx = context.WithValue(ctx, progressContextKey{}, tracker)
+
+		stop := startHeartbeats(ctx, progressHeartbeat(ctx, tracker, options))
+		defer stop()
+
+		return activityFunc(ctx, input)
+	}
+}
+
+// RecordProgress records a checkpoint in the heartbeat details, the next attempt of the activity can read
+// it with GetProgress. It can be called as often as needed, heartbeats are throttled by Cadence.
+func RecordProgress(ctx context.Context, progress interface{}) {
+	if tracker, ok := ctx.Value(progressContextKey{}).(*progressTracker); ok {
+		tracker.record(progress)
+	}
+	activity.RecordHeartbeat(ctx, progress)
+}
+
+// GetProgress reads the progress recorded by the previous attempt of the activity into progress, a
+// pointer. Returns false if there is none.
+func GetProgress(ctx context.Context, progress interface{}) (bool, error) {
+	if !activity.HasHeartbeatDetails(ctx) {
+		return false, nil
+	}
+
+	if err := activity.GetHeartbeatDetails(ctx, progress); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// progressHeartbeat returns a tick for startHeartbeats that heartbeats with the latest progress, and
+// reports it to the workflow if it changed and the ReportInterval has passed
+func progressHeartbeat(ctx context.Context, tracker *progressTracker, options HeartbeatOptions) func() {
+	var reported time.Time
+
+	return func() {
+		tracker.Lock()
+		progress, set := tracker.progress, tracker.set
+		tracker.Unlock()
+
+		if set {
+			activity.RecordHeartbeat(ctx, progress)
+		} else {
+			activity.RecordHeartbeat(ctx)
+		}
+
+		if options.Client == nil || time.Since(reported) < options.ReportInterval {
+			return
+		}
+
+		if progress, changed := tracker.latest(); changed {
+			sendProgress(ctx, options.Client, progress)
+			reported = time.Now()
+		}
+	}
+}
+
+// sendProgress signals the progress to the workflow, failures are logged because progress is best effort
+func sendProgress(ctx context.Context, c client.Client, progress interface{}) {
+	info := activity.GetInfo(ctx)
+	p := TaskProgress{
+		ActivityID: info.ActivityID,
+		Resource:   info.ActivityType.Name,
+		Attempt:    info.Attempt,
+		Progress:   progress,
+	}
+
+	execution := info.WorkflowExecution
+	if err := c.SignalWorkflow(ctx, execution.ID, execution.RunID, ProgressSignal, p); err != nil {
+		activity.GetLogger(ctx).Warn("failed to report progress to the workflow: " + err.Error())
+	}
+}
+
+// setProgressQuery collects the progress signalled by activities and answers the ProgressQuery with it
+func setProgressQuery(ctx workflow.Context) error {
+	progress := map[string]TaskProgress{}
+
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		signals := workflow.GetSignalChannel(ctx, ProgressSignal)
+		for {
+			var p TaskProgress
+			signals.Receive(ctx, &p)
+			progress[p.ActivityID] = p
+		}
+	})
+
+	return workflow.SetQueryHandler(ctx, ProgressQuery, func() ([]TaskProgress, error) {
+		var ids []string
+		for id := range progress {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		output := []TaskProgress{}
+		for _, id := range ids {
+			output = append(output, progress[id])
+		}
+		return output, nil
+	})
+}
@@ -0,0 +1,212 @@
+package aslworkflow
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/cadence/activity"
+	"go.uber.org/cadence/client"
+	"go.uber.org/cadence/encoded"
+	"go.uber.org/cadence/testsuite"
+	"go.uber.org/cadence/workflow"
+)
+
+var progressMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Wait",
+			"Seconds": 10,
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Progress_Query() {
+	sm, err := FromJSON(progressMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestProgressQueryWorkflow", *sm)
+
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(ProgressSignal, TaskProgress{ActivityID: "1", Resource: "example:activity:Import", Progress: 5})
+		s.env.SignalWorkflow(ProgressSignal, TaskProgress{ActivityID: "1", Resource: "example:activity:Import", Attempt: 1, Progress: 10})
+		s.env.SignalWorkflow(ProgressSignal, TaskProgress{ActivityID: "0", Resource: "example:activity:Export", Progress: "started"})
+	}, 5*time.Second)
+
+	s.env.ExecuteWorkflow("TestProgressQueryWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	value, err := s.env.QueryWorkflow(ProgressQuery)
+	if !s.NoError(err) {
+		return
+	}
+
+	var progress []TaskProgress
+	s.NoError(value.Get(&progress))
+	s.Equal([]TaskProgress{
+		{ActivityID: "0", Resource: "example:activity:Export", Progress: "started"},
+		{ActivityID: "1", Resource: "example:activity:Import", Attempt: 1, Progress: 10.0},
+	}, progress)
+}
+
+// progressClient records the progress signalled by HeartbeatActivity
+type progressClient struct {
+	client.Client
+
+	sync.Mutex
+	signals []TaskProgress
+}
+
+func (c *progressClient) SignalWorkflow(ctx context.Context, workflowID string, runID string, signalName string, arg interface{}) error {
+	c.Lock()
+	defer c.Unlock()
+	c.signals = append(c.signals, arg.(TaskProgress))
+	return nil
+}
+
+var registerProgressActivityOnce sync.Once
+var testProgressClient = &progressClient{}
+
+// resumeActivity continues from the offset of the previous attempt
+func resumeActivity(ctx context.Context, input interface{}) (interface{}, error) {
+	var offset int
+	if _, err := GetProgress(ctx, &offset); err != nil {
+		return nil, err
+	}
+
+	offset += 10
+	RecordProgress(ctx, offset)
+	return offset, nil
+}
+
+// slowProgressActivity records its progress and runs past a few heartbeats
+func slowProgressActivity(ctx context.Context, input interface{}) (interface{}, error) {
+	RecordProgress(ctx, 1)
+	time.Sleep(1500 * time.Millisecond)
+	return nil, nil
+}
+
+func registerProgressActivities() {
+	registerProgressActivityOnce.Do(func() {
+		RegisterActivity("TestResumeActivity", HeartbeatActivity(resumeActivity, HeartbeatOptions{Client: testProgressClient}))
+		RegisterActivity("TestSlowProgressActivity", HeartbeatActivity(slowProgressActivity, HeartbeatOptions{
+			Client:         testProgressClient,
+			ReportInterval: time.Millisecond,
+		}))
+	})
+}
+
+var heartbeatMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "TestSlowProgressActivity",
+			"HeartbeatSeconds": 1,
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Heartbeat_Activity() {
+	registerProgressActivities()
+
+	sm, err := FromJSON(heartbeatMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		return nil, workflow.ExecuteActivity(ctx, resource, input).Get(ctx, nil)
+	}
+	RegisterHandler(handler)
+
+	var heartbeats []interface{}
+	s.env.SetOnActivityHeartbeatListener(func(info *activity.Info, details encoded.Values) {
+		var progress interface{}
+		if details.HasValues() {
+			s.NoError(details.Get(&progress))
+		}
+		heartbeats = append(heartbeats, progress)
+	})
+
+	testProgressClient.Lock()
+	testProgressClient.signals = nil
+	testProgressClient.Unlock()
+
+	RegisterWorkflow("TestHeartbeatActivityWorkflow", *sm)
+	s.env.ExecuteWorkflow("TestHeartbeatActivityWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	// RecordProgress heartbeats once, the rest are sent every half second while the activity runs
+	s.True(len(heartbeats) >= 3, "heartbeats: %v", heartbeats)
+	for _, progress := range heartbeats {
+		s.Equal(1.0, progress)
+	}
+
+	// The progress only changed once, so it is reported once
+	testProgressClient.Lock()
+	defer testProgressClient.Unlock()
+	if s.Len(testProgressClient.signals, 1) {
+		s.Equal("TestSlowProgressActivity", testProgressClient.signals[0].Resource)
+		s.Equal(1, testProgressClient.signals[0].Progress)
+	}
+}
+
+func TestHeartbeatActivity(t *testing.T) {
+	registerProgressActivities()
+
+	testProgressClient.Lock()
+	testProgressClient.signals = nil
+	testProgressClient.Unlock()
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.SetHeartbeatDetails(5)
+
+	value, err := env.ExecuteActivity("TestResumeActivity", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var offset int
+	assert.NoError(t, value.Get(&offset))
+	assert.Equal(t, 15, offset)
+
+	// Without a HeartbeatTimeout there are no heartbeats to report progress on
+	testProgressClient.Lock()
+	defer testProgressClient.Unlock()
+	assert.Empty(t, testProgressClient.signals)
+}
+
+func TestProgressTracker(t *testing.T) {
+	tracker := &progressTracker{}
+
+	_, changed := tracker.latest()
+	assert.False(t, changed)
+
+	tracker.record(1)
+	progress, changed := tracker.latest()
+	assert.Equal(t, 1, progress)
+	assert.True(t, changed)
+
+	progress, changed = tracker.latest()
+	assert.Equal(t, 1, progress)
+	assert.False(t, changed)
+}
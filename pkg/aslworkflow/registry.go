@@ -0,0 +1,220 @@
+package aslworkflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// A ResourceRegistry routes the Resource of Task states to handlers, so machines hosted by the same
+// worker can use different handlers. Resources are matched by exact name first, then by the longest
+// registered prefix, then by glob patterns in the order they were registered. Globs use * as a
+// wildcard, \* matches a literal *.
+//
+//	registry := aslworkflow.NewResourceRegistry()
+//	registry.Register("example:activity:*", activityHandler)
+//	registry.RegisterPrefix("arn:aws:lambda:", lambdaHandler)
+//
+//	sm, err := aslworkflow.FromJSON(raw)
+//	err = sm.SetRegistry(registry)
+//	sm.RegisterWorkflow("example:workflow:Example")
+//
+// Machines without a registry, and resources the registry does not route, use the handler set with
+// RegisterHandler. Resources are checked when the registry is set, so a global fallback must be
+// registered first. The registry can also hold default ActivityOptions for resources, see
+// SetActivityOptions.
+
+type ResourceRegistry struct {
+	handlers resourceRoutes
+	options  resourceRoutes
+}
+
+func NewResourceRegistry() *ResourceRegistry {
+	return &ResourceRegistry{
+		handlers: newResourceRoutes(),
+		options:  newResourceRoutes(),
+	}
+}
+
+// Register routes a resource name to the handler, the name is a glob if it contains *
+func (r *ResourceRegistry) Register(resource string, handler TaskHandler) {
+	r.handlers.add(resource, handler)
+}
+
+// RegisterPrefix routes every resource starting with prefix to the handler
+func (r *ResourceRegistry) RegisterPrefix(prefix string, handler TaskHandler) {
+	r.handlers.prefixes[prefix] = handler
+}
+
+// Handler returns the handler for the resource, false if none matches
+func (r *ResourceRegistry) Handler(resource string) (TaskHandler, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	handler, ok := r.handlers.match(resource)
+	if !ok {
+		return nil, false
+	}
+	return handler.(TaskHandler), true
+}
+
+// SetActivityOptions sets the default activity options of Task states with the resource, the name is a
+// glob if it contains *. Resources are matched the same way as handlers.
+func (r *ResourceRegistry) SetActivityOptions(resource string, options ActivityOptions) {
+	r.options.add(resource, options)
+}
+
+// SetActivityOptionsPrefix sets the default activity options of every resource starting with prefix
+func (r *ResourceRegistry) SetActivityOptionsPrefix(prefix string, options ActivityOptions) {
+	r.options.prefixes[prefix] = options
+}
+
+// ActivityOptions returns the default activity options for the resource, false if none are set
+func (r *ResourceRegistry) ActivityOptions(resource string) (ActivityOptions, bool) {
+	if r == nil {
+		return ActivityOptions{}, false
+	}
+
+	options, ok := r.options.match(resource)
+	if !ok {
+		return ActivityOptions{}, false
+	}
+	return options.(ActivityOptions), true
+}
+
+// resourceRoutes matches resources by exact name, then longest prefix, then globs in order
+type resourceRoutes struct {
+	exact    map[string]interface{}
+	prefixes map[string]interface{}
+	globs    []resourceGlob
+}
+
+type resourceGlob struct {
+	pattern string
+	value   interface{}
+}
+
+func newResourceRoutes() resourceRoutes {
+	return resourceRoutes{
+		exact:    map[string]interface{}{},
+		prefixes: map[string]interface{}{},
+	}
+}
+
+func (r *resourceRoutes) add(resource string, value interface{}) {
+	if strings.Contains(resource, "*") {
+		r.globs = append(r.globs, resourceGlob{pattern: resource, value: value})
+		return
+	}
+	r.exact[resource] = value
+}
+
+func (r *resourceRoutes) match(resource string) (interface{}, bool) {
+	if value, ok := r.exact[resource]; ok {
+		return value, true
+	}
+
+	var prefixes []string
+	for prefix := range r.prefixes {
+		if strings.HasPrefix(resource, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) > 0 {
+		sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+		return r.prefixes[prefixes[0]], true
+	}
+
+	for _, glob := range r.globs {
+		if stringMatches(resource, glob.pattern) {
+			return glob.value, true
+		}
+	}
+
+	return nil, false
+}
+
+// SetRegistry attaches the registry to the machine, workflows registered from the machine route its
+// Task states with it. Returns ValidationErrors, and leaves the machine unchanged, if a Task's Resource
+// has no handler in the registry and there is no handler set with RegisterHandler to fall back to.
+func (m *StateMachine) SetRegistry(registry *ResourceRegistry) error {
+	if err := m.validate(registry); err != nil {
+		return err
+	}
+
+	m.registry = registry
+	return nil
+}
+
+// validateResources returns an error for every Task whose Resource has no handler in the registry or
+// the global handler, or invalid activity options once merged with the registry's defaults
+func (m *StateMachine) validateResources(registry *ResourceRegistry) ValidationErrors {
+	if registry == nil {
+		return nil
+	}
+
+	var errs ValidationErrors
+	for _, task := range m.Tasks() {
+		if task.Resource == nil {
+			continue
+		}
+
+		resource, _ := parseTaskResource(*task.Resource)
+
+		if defaults, ok := registry.ActivityOptions(resource); ok {
+			if err := defaults.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("%v Resource %q default ActivityOptions: %w", errorPrefix(task), *task.Resource, err))
+			}
+
+			// Each field is valid on its own, but a default LocalActivity would be ignored by integrations
+			if task.ActivityOptions.merge(defaults).isLocalActivity() && isBuiltinResource(resource) {
+				errs = append(errs, fmt.Errorf("%v LocalActivity is not supported by Resource %q", errorPrefix(task), resource))
+			}
+		}
+
+		if isBuiltinResource(resource) {
+			continue
+		}
+
+		if _, ok := registry.Handler(resource); !ok && globalTaskHandler == nil {
+			errs = append(errs, fmt.Errorf("%v Resource %q has no registered handler", errorPrefix(task), *task.Resource))
+		}
+	}
+
+	// Tasks are collected from a map, so sort for a stable order
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errs
+}
+
+const registryContextKey contextKey = "aslResourceRegistry"
+
+func withRegistry(ctx workflow.Context, registry *ResourceRegistry) workflow.Context {
+	return workflow.WithValue(ctx, registryContextKey, registry)
+}
+
+// taskHandler returns the handler for a resource, from the machine's registry if it has one, otherwise
+// the global handler
+func taskHandler(ctx workflow.Context, resource string) (TaskHandler, bool) {
+	if registry, ok := ctx.Value(registryContextKey).(*ResourceRegistry); ok {
+		if handler, ok := registry.Handler(resource); ok {
+			return handler, true
+		}
+	}
+
+	if globalTaskHandler != nil {
+		return globalTaskHandler, true
+	}
+
+	return nil, false
+}
+
+// activityOptionsDefaults returns the default activity options for a resource from the machine's registry
+func activityOptionsDefaults(ctx workflow.Context, resource string) ActivityOptions {
+	registry, _ := ctx.Value(registryContextKey).(*ResourceRegistry)
+	options, _ := registry.ActivityOptions(resource)
+	return options
+}
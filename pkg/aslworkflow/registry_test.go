@@ -0,0 +1,142 @@
+package aslworkflow
+
+import (
+	"testing"
+
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/cadence/workflow"
+)
+
+func namedHandler(name string) TaskHandler {
+	return func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		return map[string]interface{}{"handler": name, "resource": resource}, nil
+	}
+}
+
+func TestResourceRegistryHandler(t *testing.T) {
+	registry := NewResourceRegistry()
+	registry.Register("example:activity:Exact", namedHandler("exact"))
+	registry.RegisterPrefix("example:", namedHandler("short prefix"))
+	registry.RegisterPrefix("example:activity:", namedHandler("long prefix"))
+	registry.Register("arn:aws:lambda:*:function:*", namedHandler("glob"))
+
+	tests := []struct {
+		resource string
+		expected string
+	}{
+		{"example:activity:Exact", "exact"},
+		{"example:activity:Other", "long prefix"},
+		{"example:workflow:Other", "short prefix"},
+		{"arn:aws:lambda:us-east-1:function:Example", "glob"},
+		{"arn:aws:states:us-east-1:activity:Example", ""},
+	}
+
+	for _, test := range tests {
+		handler, ok := registry.Handler(test.resource)
+		if test.expected == "" {
+			assert.False(t, ok, test.resource)
+			continue
+		}
+
+		if assert.True(t, ok, test.resource) {
+			output, err := handler(nil, test.resource, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, output.(map[string]interface{})["handler"], test.resource)
+		}
+	}
+}
+
+func TestStateMachineSetRegistry(t *testing.T) {
+	sm, err := FromJSON(taskMachine)
+	assert.NoError(t, err)
+
+	DeregisterHandler()
+
+	registry := NewResourceRegistry()
+	registry.Register("example:activity:*", namedHandler("activity"))
+
+	err = sm.SetRegistry(registry)
+	assert.EqualError(t, err, "invalid state machine:\nTaskState(Example1) Error: Resource \"arn:aws:resource:example\" has no registered handler")
+	assert.Nil(t, sm.registry)
+
+	registry.RegisterPrefix("arn:aws:", namedHandler("aws"))
+	assert.NoError(t, sm.SetRegistry(registry))
+	assert.Equal(t, registry, sm.registry)
+}
+
+func TestStateMachineSetRegistryLocalActivity(t *testing.T) {
+	sm, err := FromJSON([]byte(`{
+		"StartAt": "Example1",
+		"States": {
+			"Example1": {
+				"Type": "Task",
+				"Resource": "http:invoke",
+				"End": true
+			}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	registry := NewResourceRegistry()
+	registry.SetActivityOptionsPrefix("http:", ActivityOptions{LocalActivity: to.Boolp(true)})
+
+	err = sm.SetRegistry(registry)
+	assert.EqualError(t, err, "invalid state machine:\nTaskState(Example1) Error: LocalActivity is not supported by Resource \"http:invoke\"")
+
+	// The state's own option takes precedence over the default
+	sm.States["Example1"].(*TaskState).LocalActivity = to.Boolp(false)
+	assert.NoError(t, sm.SetRegistry(registry))
+}
+
+func (s *UnitTestSuite) Test_Workflow_Resource_Registry() {
+	sm, err := FromJSON(taskMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	// The registry takes precedence over the global handler
+	RegisterHandler(namedHandler("global"))
+	defer DeregisterHandler()
+
+	registry := NewResourceRegistry()
+	registry.RegisterPrefix("arn:aws:", namedHandler("registry"))
+
+	s.NoError(RegisterWorkflowWithRegistry("TestResourceRegistryWorkflow", *sm, registry))
+
+	s.env.ExecuteWorkflow("TestResourceRegistryWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{"handler": "registry", "resource": "arn:aws:resource:example"}, result)
+}
+
+func (s *UnitTestSuite) Test_Workflow_Resource_Registry_Global_Fallback() {
+	sm, err := FromJSON(taskMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	// Resources the registry does not route fall back to the global handler
+	RegisterHandler(namedHandler("global"))
+	defer DeregisterHandler()
+
+	registry := NewResourceRegistry()
+	registry.Register("example:activity:*", namedHandler("registry"))
+
+	s.NoError(RegisterWorkflowWithRegistry("TestResourceRegistryFallbackWorkflow", *sm, registry))
+
+	s.env.ExecuteWorkflow("TestResourceRegistryFallbackWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{"handler": "global", "resource": "arn:aws:resource:example"}, result)
+}
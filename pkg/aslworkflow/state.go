@@ -2,7 +2,11 @@ package aslworkflow
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/checkr/states-language-cadence/pkg/jsonpath"
 	"github.com/coinbase/step/utils/is"
@@ -42,11 +46,22 @@ type Retrier struct {
 	IntervalSeconds *int      `json:",omitempty"`
 	MaxAttempts     *int      `json:",omitempty"`
 	BackoffRate     *float64  `json:",omitempty"`
-	attempts        int
+	MaxDelaySeconds *int      `json:",omitempty"`
+	JitterStrategy  *string   `json:",omitempty"`
 }
 
+const DefaultRetryIntervalSeconds = 1
+const DefaultRetryMaxAttempts = 3
+const DefaultRetryBackoffRate = 2.0
+
+// MaxRetryDelaySeconds caps the delay between retries, a year as in Step Functions' MaxDelaySeconds
+const MaxRetryDelaySeconds = 31622400
+
+const JitterStrategyFull = "FULL"
+const JitterStrategyNone = "NONE"
+
 func errorOutputFromError(err error) map[string]interface{} {
-	return errorOutput(to.Strp(to.ErrorType(err)), to.Strp(err.Error()))
+	return errorOutput(to.Strp(ErrorName(err)), to.Strp(ErrorCause(err)))
 }
 
 func errorOutput(err *string, cause *string) map[string]interface{} {
@@ -65,7 +80,7 @@ func errorOutput(err *string, cause *string) map[string]interface{} {
 }
 
 func errorIncluded(errorEquals []*string, err error) bool {
-	errorType := to.ErrorType(err)
+	errorType := ErrorName(err)
 
 	for _, et := range errorEquals {
 		if *et == StatesAll || *et == errorType {
@@ -100,44 +115,105 @@ func nextState(next *string, end *bool) *string {
 // Shared Methods
 //////
 
-func processRetrier(retryName *string, retriers []*Retrier, execution Execution) Execution {
+func processRetrier(retriers []*Retrier, execution Execution) Execution {
 	return func(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
-		// Simulate Retry once, not actually waiting
-		output, next, err := execution(ctx, input)
-		if len(retriers) == 0 || err == nil {
-			return output, next, err
-		}
+		// Each Retrier keeps its own count of attempts for this execution of the state
+		attempts := make([]int, len(retriers))
+		retryCount := 0
 
-		// Is Error in a Retrier
-		for _, retrier := range retriers {
-			// If the error type is defined in the retrier AND we have not attempted the retry yet
-			if retrier.MaxAttempts == nil {
-				// Default retries is 3
-				retrier.MaxAttempts = to.Intp(3)
+		for {
+			output, next, err := execution(withRetryCount(ctx, retryCount), input)
+			if len(retriers) == 0 || err == nil || isCancellation(ctx, err) {
+				return output, next, err
 			}
 
 			// Match on first retrier
-			if errorIncluded(retrier.ErrorEquals, err) {
-				if retrier.attempts < *retrier.MaxAttempts {
-					retrier.attempts++
-					// Returns the name of the state to the state-machine to re-execute
-					return input, retryName, nil
-				}
+			i := matchingRetrier(retriers, err)
+			if i < 0 {
+				return output, next, err
+			}
+
+			retrier := retriers[i]
+			if attempts[i] >= retrier.maxAttempts() {
 				// Finished retrying so continue
 				return output, next, err
 			}
+
+			delay := retrier.delay(ctx, attempts[i])
+			attempts[i]++
+			retryCount++
+
+			// Durable timer so the wait survives worker restarts
+			if err := workflow.Sleep(ctx, delay); err != nil {
+				return nil, nil, err
+			}
 		}
+	}
+}
 
-		// Otherwise, just return
-		return output, next, err
+// isCancellation returns true if the error is caused by the state being cancelled, which is passed on
+// without Retry or Catch
+func isCancellation(ctx workflow.Context, err error) bool {
+	return isCanceled(err) || ctx.Err() != nil
+}
+
+func matchingRetrier(retriers []*Retrier, err error) int {
+	for i, retrier := range retriers {
+		if errorIncluded(retrier.ErrorEquals, err) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Retrier) maxAttempts() int {
+	if r.MaxAttempts == nil {
+		return DefaultRetryMaxAttempts
+	}
+	return *r.MaxAttempts
+}
+
+// delay returns how long to wait before the retry following the given number of previous attempts
+func (r *Retrier) delay(ctx workflow.Context, attempt int) time.Duration {
+	interval := float64(DefaultRetryIntervalSeconds)
+	if r.IntervalSeconds != nil {
+		interval = float64(*r.IntervalSeconds)
+	}
+
+	backoffRate := DefaultRetryBackoffRate
+	if r.BackoffRate != nil {
+		backoffRate = *r.BackoffRate
 	}
+
+	seconds := interval * math.Pow(backoffRate, float64(attempt))
+	if r.MaxDelaySeconds != nil && seconds > float64(*r.MaxDelaySeconds) {
+		seconds = float64(*r.MaxDelaySeconds)
+	}
+
+	// Clamp before converting, large attempts overflow time.Duration
+	if seconds > MaxRetryDelaySeconds {
+		seconds = MaxRetryDelaySeconds
+	}
+
+	if r.JitterStrategy != nil && *r.JitterStrategy == JitterStrategyFull {
+		// Random values must be recorded so replays compute the same delay
+		var jitter float64
+		encodedJitter := workflow.SideEffect(ctx, func(ctx workflow.Context) interface{} {
+			return rand.Float64()
+		})
+		if err := encodedJitter.Get(&jitter); err == nil {
+			seconds = seconds * jitter
+		}
+	}
+
+	return time.Duration(seconds * float64(time.Second))
 }
 
 func processCatcher(catchers []*Catcher, execution Execution) Execution {
 	return func(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
 		output, next, err := execution(ctx, input)
 
-		if len(catchers) == 0 || err == nil {
+		if len(catchers) == 0 || err == nil || isCancellation(ctx, err) {
 			return output, next, err
 		}
 
@@ -169,7 +245,7 @@ func processError(s State, execution Execution) Execution {
 
 func processInputOutput(inputPath *jsonpath.Path, outputPath *jsonpath.Path, execution Execution) Execution {
 	return func(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
-		input, err := inputPath.Get(input)
+		input, err := getPath(ctx, inputPath, input)
 
 		if err != nil {
 			return nil, nil, fmt.Errorf("Input Error: %v", err)
@@ -181,7 +257,7 @@ func processInputOutput(inputPath *jsonpath.Path, outputPath *jsonpath.Path, exe
 			return nil, nil, err
 		}
 
-		output, err = outputPath.Get(output)
+		output, err = getPath(ctx, outputPath, output)
 
 		if err != nil {
 			return nil, nil, fmt.Errorf("Output Error: %v", err)
@@ -197,7 +273,7 @@ func processParams(params interface{}, execution Execution) Execution {
 			return execution(ctx, input)
 		}
 		// Loop through the input replace values with JSON paths
-		input, err := replaceParamsJSONPath(params, input)
+		input, err := replaceParamsJSONPath(ctx, params, input)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -206,32 +282,37 @@ func processParams(params interface{}, execution Execution) Execution {
 	}
 }
 
-func replaceParamsJSONPath(params interface{}, input interface{}) (interface{}, error) {
+func replaceParamsJSONPath(ctx workflow.Context, params interface{}, input interface{}) (interface{}, error) {
 	switch params.(type) {
 	case map[string]interface{}:
 		newParams := map[string]interface{}{}
+
+		// Intrinsics such as States.UUID record side effects, which are replayed in the order they were
+		// made, so keys must always be resolved in the same order
+		var keys []string
+		for key := range params.(map[string]interface{}) {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
 		// Recurse over params find keys to replace
-		for key, value := range params.(map[string]interface{}) {
+		for _, key := range keys {
+			value := params.(map[string]interface{})[key]
 			if strings.HasSuffix(key, ".$") {
 				key = key[:len(key)-len(".$")]
-				// value must be a JSON path string!
+				// value must be a JSON path or intrinsic function string!
 				switch value.(type) {
 				case string:
 				default:
 					return nil, fmt.Errorf("value to key %q is not string", key)
 				}
-				valueStr := value.(string)
-				path, err := jsonpath.NewPath(valueStr)
+				newValue, err := resolveParamValue(ctx, value.(string), input)
 				if err != nil {
-					return nil, errors.Wrap(err, "failed parsing path")
-				}
-				newValue, err := path.Get(input)
-				if err != nil {
-					return nil, errors.Wrap(err, "failed getting path")
+					return nil, err
 				}
 				newParams[key] = newValue
 			} else {
-				newValue, err := replaceParamsJSONPath(value, input)
+				newValue, err := replaceParamsJSONPath(ctx, value, input)
 				if err != nil {
 					return nil, errors.Wrap(err, "failed replacing path")
 				}
@@ -243,6 +324,49 @@ func replaceParamsJSONPath(params interface{}, input interface{}) (interface{},
 	return params, nil
 }
 
+// resolveParamValue returns the value of a "key.$" field, either a JSON path or an intrinsic function
+func resolveParamValue(ctx workflow.Context, valueStr string, input interface{}) (interface{}, error) {
+	if isIntrinsic(valueStr) {
+		call, err := parseIntrinsic(valueStr)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed parsing intrinsic function")
+		}
+		newValue, err := call.evaluate(ctx, input)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed evaluating intrinsic function")
+		}
+		return newValue, nil
+	}
+
+	path, err := jsonpath.NewPath(valueStr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing path")
+	}
+	newValue, err := getPath(ctx, path, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed getting path")
+	}
+	return newValue, nil
+}
+
+func processResultSelector(resultSelector interface{}, execution Execution) Execution {
+	return func(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
+		result, next, err := execution(ctx, input)
+
+		if err != nil || resultSelector == nil {
+			return result, next, err
+		}
+
+		// Reshape the raw result with the same templating as Parameters
+		result, err = replaceParamsJSONPath(ctx, resultSelector, result)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ResultSelector Error: %w", err)
+		}
+
+		return result, next, nil
+	}
+}
+
 func processResult(resultPath *jsonpath.Path, execution Execution) Execution {
 	return func(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
 		result, next, err := execution(ctx, input)
@@ -255,7 +379,7 @@ func processResult(resultPath *jsonpath.Path, execution Execution) Execution {
 			input, err := resultPath.Set(input, result)
 
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, newStatesError(StatesResultPathMatchFailure, err.Error())
 			}
 
 			return input, next, nil
@@ -305,6 +429,76 @@ func ValidateNameAndType(s State) error {
 	return nil
 }
 
+func isResultSelectorValid(resultSelector interface{}) error {
+	if resultSelector == nil {
+		return nil
+	}
+
+	if _, ok := resultSelector.(map[string]interface{}); !ok {
+		return fmt.Errorf("ResultSelector must be an object")
+	}
+
+	if err := isParamsValid(resultSelector); err != nil {
+		return fmt.Errorf("ResultSelector %w", err)
+	}
+
+	return nil
+}
+
+// isTemplateValid checks a Parameters style field, e.g. Parameters or ItemSelector
+func isTemplateValid(name string, template interface{}) error {
+	if err := isParamsValid(template); err != nil {
+		return fmt.Errorf("%v %w", name, err)
+	}
+	return nil
+}
+
+// isParamsValid checks that every "key.$" in a Parameters style template has a valid path or intrinsic function as its value
+func isParamsValid(params interface{}) error {
+	switch params := params.(type) {
+	case map[string]interface{}:
+		for key, value := range params {
+			if !strings.HasSuffix(key, ".$") {
+				if err := isParamsValid(value); err != nil {
+					return err
+				}
+				continue
+			}
+
+			valueStr, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("value to key %q is not string", key)
+			}
+
+			if isIntrinsic(valueStr) {
+				if _, err := parseIntrinsic(valueStr); err != nil {
+					return fmt.Errorf("value to key %q: %w", key, err)
+				}
+			} else if _, err := jsonpath.NewPath(valueStr); err != nil {
+				return fmt.Errorf("value to key %q: %w", key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isReferencePathValid checks a path that must select a single value, e.g. ResultPath, is not a query
+func isReferencePathValid(name string, path *jsonpath.Path) error {
+	if !path.IsReference() {
+		return fmt.Errorf("%v must be a reference path", name)
+	}
+	return nil
+}
+
+// isResultPathValid checks a path results are written to, it must be a reference path into the input
+func isResultPathValid(name string, path *jsonpath.Path) error {
+	if path.IsContext() {
+		return fmt.Errorf("%v must not refer to the context object", name)
+	}
+	return isReferencePathValid(name, path)
+}
+
 func isRetryValid(retry []*Retrier) error {
 	if retry == nil {
 		return nil
@@ -314,6 +508,30 @@ func isRetryValid(retry []*Retrier) error {
 		if err := isErrorEqualsValid(r.ErrorEquals, len(retry)-1 == i); err != nil {
 			return err
 		}
+
+		if r.IntervalSeconds != nil && *r.IntervalSeconds < 1 {
+			return fmt.Errorf("Retrier IntervalSeconds must be at least 1")
+		}
+
+		if r.MaxAttempts != nil && *r.MaxAttempts < 0 {
+			return fmt.Errorf("Retrier MaxAttempts must not be negative")
+		}
+
+		if r.BackoffRate != nil && *r.BackoffRate < 1.0 {
+			return fmt.Errorf("Retrier BackoffRate must be at least 1.0")
+		}
+
+		if r.MaxDelaySeconds != nil && *r.MaxDelaySeconds < 1 {
+			return fmt.Errorf("Retrier MaxDelaySeconds must be at least 1")
+		}
+
+		if r.JitterStrategy != nil {
+			switch *r.JitterStrategy {
+			case JitterStrategyFull, JitterStrategyNone:
+			default:
+				return fmt.Errorf("Unknown Retrier JitterStrategy %q", *r.JitterStrategy)
+			}
+		}
 	}
 
 	return nil
@@ -332,6 +550,10 @@ func isCatchValid(catch []*Catcher) error {
 		if is.EmptyStr(c.Next) {
 			return fmt.Errorf("Catcher requires Next")
 		}
+
+		if err := isResultPathValid("Catcher ResultPath", c.ResultPath); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -343,6 +565,7 @@ const StatesPermissions = "States.Permissions"
 const StatesResultPathMatchFailure = "States.ResultPathMatchFailure"
 const StatesBranchFailed = "States.BranchFailed"
 const StatesNoChoiceMatched = "States.NoChoiceMatched"
+const StatesRuntime = "States.Runtime"
 
 func isErrorEqualsValid(errorEquals []*string, last bool) error {
 	if len(errorEquals) == 0 {
@@ -360,7 +583,8 @@ func isErrorEqualsValid(errorEquals []*string, last bool) error {
 				StatesPermissions,
 				StatesResultPathMatchFailure,
 				StatesBranchFailed,
-				StatesNoChoiceMatched:
+				StatesNoChoiceMatched,
+				StatesRuntime:
 			default:
 				return fmt.Errorf("Unknown States.* error found %q", *e)
 			}
@@ -3,6 +3,7 @@ package aslworkflow
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/checkr/states-language-cadence/pkg/jsonpath"
 	"github.com/coinbase/step/utils/to"
@@ -20,6 +21,8 @@ type TaskState struct {
 	ResultPath *jsonpath.Path `json:",omitempty"`
 	Parameters interface{}    `json:",omitempty"`
 
+	ResultSelector interface{} `json:",omitempty"`
+
 	Resource *string `json:",omitempty"`
 
 	Catch []*Catcher `json:",omitempty"`
@@ -30,33 +33,85 @@ type TaskState struct {
 
 	TimeoutSeconds   int `json:",omitempty"`
 	HeartbeatSeconds int `json:",omitempty"`
+
+	TimeoutSecondsPath   *jsonpath.Path `json:",omitempty"`
+	HeartbeatSecondsPath *jsonpath.Path `json:",omitempty"`
+
+	ChildWorkflowOptions *ChildWorkflowOptions `json:",omitempty"`
+
+	ActivityOptions // TaskList, ScheduleToStartTimeoutSeconds, WaitForCancellation and RetryPolicy
 }
 
 var ErrTaskHandlerNotRegistered = errors.New("handler has not been registered")
 
 func (s *TaskState) process(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
-	if globalTaskHandler != nil {
-		result, err := globalTaskHandler(ctx, *s.Resource, input)
+	resource, waitForTaskToken := parseTaskResource(*s.Resource)
+
+	result, err := s.run(ctx, resource, input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if waitForTaskToken {
+		// The result of the resource is replaced by the callback output
+		result, err = waitForTaskCallback(ctx)
 		if err != nil {
 			return nil, nil, err
 		}
-		return result, nextState(s.Next, s.End), nil
 	}
 
-	return nil, nil, ErrTaskHandlerNotRegistered
+	return result, nextState(s.Next, s.End), nil
+}
+
+// run executes the resource with a built in integration, a local activity or a handler
+func (s *TaskState) run(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+	if integration, ok := builtinIntegration(resource); ok {
+		result, err := integration(ctx, s, resource, input)
+		if err != nil {
+			return nil, taskFailed(err)
+		}
+		return result, nil
+	}
+
+	if s.ActivityOptions.merge(activityOptionsDefaults(ctx, resource)).isLocalActivity() {
+		activityFunc, ok := localActivity(resource)
+		if !ok {
+			return nil, ErrLocalActivityNotRegistered
+		}
+
+		result, err := executeLocalActivity(ctx, activityFunc, input)
+		if err != nil {
+			return nil, taskFailed(err)
+		}
+		return result, nil
+	}
+
+	if handler, ok := taskHandler(ctx, resource); ok {
+		result, err := handler(ctx, resource, input)
+		if err != nil {
+			return nil, taskFailed(err)
+		}
+		return result, nil
+	}
+
+	return nil, ErrTaskHandlerNotRegistered
 }
 
 // Input must include the Task name in $.Task
 func (s *TaskState) Execute(ctx workflow.Context, input interface{}) (output interface{}, next *string, err error) {
 	return processError(s,
 		processCatcher(s.Catch,
-			processRetrier(s.Name(), s.Retry,
-				processInputOutput(
-					s.InputPath,
-					s.OutputPath,
-					processParams(
-						s.Parameters,
-						processResult(s.ResultPath, s.process),
+			processRetrier(s.Retry,
+				s.processTaskToken(
+					processInputOutput(
+						s.InputPath,
+						s.OutputPath,
+						processParams(
+							s.Parameters,
+							s.processActivityOptions(
+								processResult(s.ResultPath, processResultSelector(s.ResultSelector, s.process)),
+							),
+						),
 					),
 				),
 			),
@@ -64,6 +119,59 @@ func (s *TaskState) Execute(ctx workflow.Context, input interface{}) (output int
 	)(ctx, input)
 }
 
+// processActivityOptions applies the state's timeouts and activity options, merged with the defaults
+// for its resource, to the activity options used by the handler. TimeoutSecondsPath and
+// HeartbeatSecondsPath are read from the effective input, after InputPath and Parameters.
+func (s *TaskState) processActivityOptions(execution Execution) Execution {
+	return func(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
+		timeout, heartbeat, err := s.timeouts(ctx, input)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resource, waitForTaskToken := parseTaskResource(*s.Resource)
+
+		// Callbacks heartbeat rather than the activity when waiting for a task token
+		activityHeartbeat := heartbeat
+		if waitForTaskToken {
+			activityHeartbeat = 0
+		}
+
+		options := s.ActivityOptions.merge(activityOptionsDefaults(ctx, resource))
+		ctx = withTaskActivityOptions(ctx, options, timeout, activityHeartbeat)
+		ctx = withLocalActivityOptions(ctx, getActivityOptions(ctx))
+
+		return execution(withTaskTimeouts(ctx, timeout, heartbeat), input)
+	}
+}
+
+// timeouts returns the TimeoutSeconds and HeartbeatSeconds of the state, 0 if they are not set
+func (s *TaskState) timeouts(ctx workflow.Context, input interface{}) (timeout time.Duration, heartbeat time.Duration, err error) {
+	timeout = time.Duration(s.TimeoutSeconds) * time.Second
+	if s.TimeoutSecondsPath != nil {
+		secs, err := s.TimeoutSecondsPath.GetNumber(pathSource(ctx, s.TimeoutSecondsPath, input))
+		if err != nil {
+			return 0, 0, fmt.Errorf("TimeoutSecondsPath Error: %w", err)
+		}
+		timeout = time.Duration(*secs * float64(time.Second))
+	}
+
+	heartbeat = time.Duration(s.HeartbeatSeconds) * time.Second
+	if s.HeartbeatSecondsPath != nil {
+		secs, err := s.HeartbeatSecondsPath.GetNumber(pathSource(ctx, s.HeartbeatSecondsPath, input))
+		if err != nil {
+			return 0, 0, fmt.Errorf("HeartbeatSecondsPath Error: %w", err)
+		}
+		heartbeat = time.Duration(*secs * float64(time.Second))
+	}
+
+	if timeout < 0 || heartbeat < 0 {
+		return 0, 0, fmt.Errorf("TimeoutSeconds and HeartbeatSeconds must be positive")
+	}
+
+	return timeout, heartbeat, nil
+}
+
 func (s *TaskState) Validate() error {
 	s.SetType(to.Strp("Task"))
 
@@ -83,6 +191,34 @@ func (s *TaskState) Validate() error {
 	//if s.taskHandler != nil {
 	//}
 
+	if err := isResultPathValid("ResultPath", s.ResultPath); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isTemplateValid("Parameters", s.Parameters); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := s.isTimeoutValid(); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := s.isChildWorkflowValid(); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := s.ActivityOptions.Validate(); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if resource, _ := parseTaskResource(*s.Resource); s.isLocalActivity() && isBuiltinResource(resource) {
+		return fmt.Errorf("%v LocalActivity is not supported by Resource %q", errorPrefix(s), resource)
+	}
+
+	if err := isResultSelectorValid(s.ResultSelector); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
 	if err := isCatchValid(s.Catch); err != nil {
 		return err
 	}
@@ -94,6 +230,30 @@ func (s *TaskState) Validate() error {
 	return nil
 }
 
+func (s *TaskState) isTimeoutValid() error {
+	if s.TimeoutSeconds != 0 && s.TimeoutSecondsPath != nil {
+		return fmt.Errorf("Only One (TimeoutSeconds,TimeoutSecondsPath)")
+	}
+
+	if s.HeartbeatSeconds != 0 && s.HeartbeatSecondsPath != nil {
+		return fmt.Errorf("Only One (HeartbeatSeconds,HeartbeatSecondsPath)")
+	}
+
+	if s.TimeoutSeconds < 0 || s.HeartbeatSeconds < 0 {
+		return fmt.Errorf("TimeoutSeconds and HeartbeatSeconds must be positive")
+	}
+
+	if s.HeartbeatSeconds != 0 && s.TimeoutSeconds != 0 && s.HeartbeatSeconds >= s.TimeoutSeconds {
+		return fmt.Errorf("HeartbeatSeconds must be smaller than TimeoutSeconds")
+	}
+
+	if err := isReferencePathValid("TimeoutSecondsPath", s.TimeoutSecondsPath); err != nil {
+		return err
+	}
+
+	return isReferencePathValid("HeartbeatSecondsPath", s.HeartbeatSecondsPath)
+}
+
 func (s *TaskState) SetType(t *string) {
 	s.Type = t
 }
@@ -3,7 +3,11 @@ package aslworkflow
 import (
 	"errors"
 	"strings"
+	"testing"
+	"time"
 
+	"github.com/coinbase/step/utils/to"
+	"github.com/stretchr/testify/assert"
 	"go.uber.org/cadence/workflow"
 )
 
@@ -96,3 +100,158 @@ func (s *UnitTestSuite) Test_Workflow_Task_State_Missing() {
 	}
 
 }
+
+var taskRetryMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "arn:aws:resource:example",
+			"Retry": [
+				{
+					"ErrorEquals": ["States.ALL"],
+					"IntervalSeconds": 2,
+					"BackoffRate": 3,
+					"MaxDelaySeconds": 10,
+					"MaxAttempts": 3
+				}
+			],
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Task_State_Retry_Backoff() {
+	sm, err := FromJSON(taskRetryMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	calls := 0
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		calls++
+		if calls < 4 {
+			return nil, errors.New("task error")
+		}
+		return map[string]interface{}{"test": "example_output"}, nil
+	}
+	RegisterHandler(handler)
+
+	var delays []time.Duration
+	s.env.SetOnTimerScheduledListener(func(timerID string, d time.Duration) {
+		delays = append(delays, d)
+	})
+
+	RegisterWorkflow("TestTaskRetryWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestTaskRetryWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(4, calls)
+
+	// 2s, 2s*3, 2s*3*3 capped at 10s
+	s.Equal([]time.Duration{2 * time.Second, 6 * time.Second, 10 * time.Second}, delays)
+}
+
+func (s *UnitTestSuite) Test_Workflow_Task_State_Retry_Exhausted() {
+	sm, err := FromJSON(taskRetryMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	calls := 0
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		calls++
+		return nil, errors.New("task error")
+	}
+	RegisterHandler(handler)
+
+	RegisterWorkflow("TestTaskRetryExhaustedWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestTaskRetryExhaustedWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.Error(s.env.GetWorkflowError())
+	s.Equal(4, calls)
+}
+
+var taskResultSelectorMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "arn:aws:resource:example",
+			"ResultSelector": {
+				"id.$": "$.Payload.id",
+				"source": "example"
+			},
+			"ResultPath": "$.result",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Task_State_ResultSelector() {
+	sm, err := FromJSON(taskResultSelectorMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+	s.NoError(sm.States["Example1"].Validate())
+
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		output := map[string]interface{}{
+			"StatusCode": 200,
+			"Payload":    map[string]interface{}{"id": "abc", "large": "envelope"},
+		}
+		return output, nil
+	}
+	RegisterHandler(handler)
+
+	exampleInput := map[string]interface{}{"test": "example_input"}
+
+	RegisterWorkflow("TestTaskResultSelectorWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestTaskResultSelectorWorkflow", exampleInput)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	err = s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+
+	s.Equal("example_input", result["test"])
+	s.Equal(map[string]interface{}{"id": "abc", "source": "example"}, result["result"])
+}
+
+func TestTaskStateResultSelectorValidate(t *testing.T) {
+	state := &TaskState{
+		Resource:       to.Strp("arn:aws:resource:example"),
+		End:            to.Boolp(true),
+		ResultSelector: map[string]interface{}{"id.$": "not a path"},
+	}
+	state.SetName(to.Strp("Example1"))
+	assert.Error(t, state.Validate())
+
+	state.ResultSelector = []interface{}{"not an object"}
+	assert.Error(t, state.Validate())
+
+	state.ResultSelector = map[string]interface{}{"id.$": "$.id"}
+	assert.NoError(t, state.Validate())
+}
+
+func TestRetrierDelayLargeAttempt(t *testing.T) {
+	retrier := &Retrier{IntervalSeconds: to.Intp(1), BackoffRate: to.Float64p(2.0)}
+
+	assert.Equal(t, 4*time.Second, retrier.delay(nil, 2))
+	assert.Equal(t, MaxRetryDelaySeconds*time.Second, retrier.delay(nil, 100))
+	assert.Equal(t, MaxRetryDelaySeconds*time.Second, retrier.delay(nil, 10000))
+}
@@ -0,0 +1,280 @@
+package aslworkflow
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/cadence/client"
+	"go.uber.org/cadence/workflow"
+)
+
+//
+// Task states whose Resource ends with .waitForTaskToken run the resource with a task token available
+// as $$.Task.Token, then wait for an external system to call back with SendTaskSuccess or
+// SendTaskFailure. The callback output is the result of the state. If HeartbeatSeconds is set
+// SendTaskHeartbeat must be called at least that often, and TimeoutSeconds limits the whole wait,
+// otherwise the state fails with States.Timeout.
+//
+//	"WaitForReview": {
+//		"Type": "Task",
+//		"Resource": "example:activity:RequestReview.waitForTaskToken",
+//		"Parameters": {
+//			"document.$": "$.document",
+//			"token.$": "$$.Task.Token"
+//		},
+//		"HeartbeatSeconds": 3600,
+//		"End": true
+//	}
+
+const TaskTokenResourceSuffix = ".waitForTaskToken"
+
+// TaskTokenSignal is the signal callbacks are sent to the workflow with
+const TaskTokenSignal = "aslTaskToken"
+
+const taskCallbackSuccess = "SUCCESS"
+const taskCallbackFailure = "FAILURE"
+const taskCallbackHeartbeat = "HEARTBEAT"
+
+// TaskToken identifies the Task state waiting for a callback
+type TaskToken struct {
+	WorkflowID string
+	RunID      string
+	State      string
+	ID         string // Unique for each time the state is run
+}
+
+// String encodes the token so it can be passed to external systems
+func (t TaskToken) String() string {
+	raw, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// ParseTaskToken decodes a token created by a Task state
+func ParseTaskToken(token string) (*TaskToken, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid task token: %w", err)
+	}
+
+	var t TaskToken
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return nil, fmt.Errorf("invalid task token: %w", err)
+	}
+
+	if t.WorkflowID == "" || t.ID == "" {
+		return nil, fmt.Errorf("invalid task token: missing workflow or ID")
+	}
+
+	return &t, nil
+}
+
+// TaskCallback is the payload of the TaskTokenSignal
+type TaskCallback struct {
+	Token  string
+	Status string
+
+	Output interface{} `json:",omitempty"`
+	Error  string      `json:",omitempty"`
+	Cause  string      `json:",omitempty"`
+}
+
+// SendTaskSuccess completes the Task state waiting on the token with the output
+func SendTaskSuccess(ctx context.Context, c client.Client, token string, output interface{}) error {
+	return sendTaskCallback(ctx, c, TaskCallback{Token: token, Status: taskCallbackSuccess, Output: output})
+}
+
+// SendTaskFailure fails the Task state waiting on the token with the error name and cause
+func SendTaskFailure(ctx context.Context, c client.Client, token string, errorName string, cause string) error {
+	return sendTaskCallback(ctx, c, TaskCallback{Token: token, Status: taskCallbackFailure, Error: errorName, Cause: cause})
+}
+
+// SendTaskHeartbeat tells the Task state waiting on the token that the work is still in progress
+func SendTaskHeartbeat(ctx context.Context, c client.Client, token string) error {
+	return sendTaskCallback(ctx, c, TaskCallback{Token: token, Status: taskCallbackHeartbeat})
+}
+
+func sendTaskCallback(ctx context.Context, c client.Client, callback TaskCallback) error {
+	t, err := ParseTaskToken(callback.Token)
+	if err != nil {
+		return err
+	}
+	return c.SignalWorkflow(ctx, t.WorkflowID, t.RunID, TaskTokenSignal, callback)
+}
+
+// parseTaskResource returns the resource to run and whether to wait for a task token callback
+func parseTaskResource(resource string) (string, bool) {
+	if strings.HasSuffix(resource, TaskTokenResourceSuffix) {
+		return strings.TrimSuffix(resource, TaskTokenResourceSuffix), true
+	}
+	return resource, false
+}
+
+// processTaskToken creates the task token before Parameters are evaluated, so they can use $$.Task.Token.
+// Callbacks for the token are collected from then on, so one sent before the state starts waiting is kept.
+func (s *TaskState) processTaskToken(execution Execution) Execution {
+	return func(ctx workflow.Context, input interface{}) (interface{}, *string, error) {
+		if _, wait := parseTaskResource(*s.Resource); !wait {
+			return execution(ctx, input)
+		}
+
+		callbacks, ok := ctx.Value(taskCallbacksContextKey).(*taskCallbacks)
+		if !ok {
+			return nil, nil, fmt.Errorf("task token callbacks are only received by workflows registered with RegisterWorkflow")
+		}
+
+		var id string
+		encodedID := workflow.SideEffect(ctx, func(ctx workflow.Context) interface{} {
+			return newUUID()
+		})
+		if err := encodedID.Get(&id); err != nil {
+			return nil, nil, err
+		}
+
+		info := workflow.GetInfo(ctx)
+		token := TaskToken{
+			WorkflowID: info.WorkflowExecution.ID,
+			RunID:      info.WorkflowExecution.RunID,
+			State:      getExecutionContext(ctx).stateName,
+			ID:         id,
+		}.String()
+
+		callbacks.register(ctx, token)
+		defer callbacks.unregister(token)
+
+		return execution(withTaskToken(ctx, token), input)
+	}
+}
+
+const taskCallbacksContextKey contextKey = "aslTaskCallbacks"
+
+// taskCallbacks routes the callbacks received on the TaskTokenSignal to the Task state waiting for their
+// token, so any number of states, e.g. Map iterations, can wait at the same time. Callbacks for tokens
+// no state is waiting for, e.g. from an earlier attempt of the state, are dropped.
+type taskCallbacks struct {
+	waiters map[string]*taskCallbackWaiter
+}
+
+type taskCallbackWaiter struct {
+	pending []TaskCallback
+	notify  workflow.Channel // Has a value when callbacks are pending
+}
+
+// withTaskCallbacks starts receiving the TaskTokenSignal for the workflow
+func withTaskCallbacks(ctx workflow.Context) workflow.Context {
+	callbacks := &taskCallbacks{waiters: map[string]*taskCallbackWaiter{}}
+
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		signals := workflow.GetSignalChannel(ctx, TaskTokenSignal)
+		for {
+			var callback TaskCallback
+			signals.Receive(ctx, &callback)
+
+			if waiter, ok := callbacks.waiters[callback.Token]; ok {
+				waiter.pending = append(waiter.pending, callback)
+				waiter.notify.SendAsync(true)
+			}
+		}
+	})
+
+	return workflow.WithValue(ctx, taskCallbacksContextKey, callbacks)
+}
+
+func (c *taskCallbacks) register(ctx workflow.Context, token string) {
+	c.waiters[token] = &taskCallbackWaiter{notify: workflow.NewBufferedChannel(ctx, 1)}
+}
+
+func (c *taskCallbacks) unregister(token string) {
+	delete(c.waiters, token)
+}
+
+// waitForTaskCallback blocks until a success or failure callback for the current task token is received
+func waitForTaskCallback(ctx workflow.Context) (interface{}, error) {
+	token := getExecutionContext(ctx).taskToken
+	timeouts := getTaskTimeouts(ctx)
+
+	callbacks, _ := ctx.Value(taskCallbacksContextKey).(*taskCallbacks)
+	waiter, ok := callbacks.waiters[token]
+	if !ok {
+		return nil, fmt.Errorf("no task token to wait for")
+	}
+
+	timerCtx, cancelTimers := workflow.WithCancel(ctx)
+	defer cancelTimers()
+
+	var timeoutTimer workflow.Future
+	if timeouts.timeout > 0 {
+		timeoutTimer = workflow.NewTimer(timerCtx, timeouts.timeout)
+	}
+
+	// The heartbeat timer is restarted by every heartbeat
+	startHeartbeat := func() (workflow.Future, func()) {
+		if timeouts.heartbeat <= 0 {
+			return nil, func() {}
+		}
+		heartbeatCtx, cancelHeartbeat := workflow.WithCancel(timerCtx)
+		return workflow.NewTimer(heartbeatCtx, timeouts.heartbeat), cancelHeartbeat
+	}
+	heartbeatTimer, cancelHeartbeat := startHeartbeat()
+
+	for {
+		for len(waiter.pending) > 0 {
+			callback := waiter.pending[0]
+			waiter.pending = waiter.pending[1:]
+
+			switch callback.Status {
+			case taskCallbackSuccess:
+				return callback.Output, nil
+			case taskCallbackFailure:
+				if callback.Error == "" {
+					callback.Error = StatesTaskFailed
+				}
+				return nil, newStatesError(callback.Error, callback.Cause)
+			case taskCallbackHeartbeat:
+				cancelHeartbeat()
+				heartbeatTimer, cancelHeartbeat = startHeartbeat()
+			}
+		}
+
+		var timeoutErr error
+
+		selector := workflow.NewSelector(ctx)
+		selector.AddReceive(waiter.notify, func(c workflow.Channel, more bool) {
+			c.Receive(ctx, nil)
+		})
+		if timeoutTimer != nil {
+			selector.AddFuture(timeoutTimer, func(f workflow.Future) {
+				timeoutErr = newStatesError(StatesTimeout, fmt.Sprintf("no callback received within %v", timeouts.timeout))
+			})
+		}
+		if heartbeatTimer != nil {
+			selector.AddFuture(heartbeatTimer, func(f workflow.Future) {
+				timeoutErr = newStatesError(StatesTimeout, fmt.Sprintf("no heartbeat received within %v", timeouts.heartbeat))
+			})
+		}
+		selector.Select(ctx)
+
+		if timeoutErr != nil {
+			return nil, timeoutErr
+		}
+	}
+}
+
+const taskTimeoutsContextKey contextKey = "aslTaskTimeouts"
+
+type taskTimeouts struct {
+	timeout   time.Duration
+	heartbeat time.Duration
+}
+
+func withTaskTimeouts(ctx workflow.Context, timeout time.Duration, heartbeat time.Duration) workflow.Context {
+	return workflow.WithValue(ctx, taskTimeoutsContextKey, taskTimeouts{timeout: timeout, heartbeat: heartbeat})
+}
+
+func getTaskTimeouts(ctx workflow.Context) taskTimeouts {
+	timeouts, _ := ctx.Value(taskTimeoutsContextKey).(taskTimeouts)
+	return timeouts
+}
@@ -0,0 +1,219 @@
+package aslworkflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/cadence"
+	"go.uber.org/cadence/workflow"
+)
+
+var taskTokenMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "example:activity:RequestReview.waitForTaskToken",
+			"Parameters": {
+				"token.$": "$$.Task.Token"
+			},
+			"HeartbeatSeconds": 60,
+			"ResultPath": "$.review",
+			"End": true
+		}
+	}
+}
+`)
+
+// registerTaskTokenHandler records the token sent to the handler
+func registerTaskTokenHandler(s *UnitTestSuite, token *string) {
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		s.Equal("example:activity:RequestReview", resource)
+		*token = input.(map[string]interface{})["token"].(string)
+		return map[string]interface{}{"ignored": true}, nil
+	}
+	RegisterHandler(handler)
+}
+
+func (s *UnitTestSuite) Test_Workflow_Task_Token_Success() {
+	sm, err := FromJSON(taskTokenMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	var token string
+	registerTaskTokenHandler(s, &token)
+	RegisterWorkflow("TestTaskTokenSuccessWorkflow", *sm)
+
+	// Heartbeats keep the state waiting past HeartbeatSeconds
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(TaskTokenSignal, TaskCallback{Token: token, Status: taskCallbackHeartbeat})
+	}, 50*time.Second)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(TaskTokenSignal, TaskCallback{Token: "stale", Status: taskCallbackSuccess})
+	}, 90*time.Second)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(TaskTokenSignal, TaskCallback{Token: token, Status: taskCallbackSuccess, Output: "approved"})
+	}, 100*time.Second)
+
+	startTime := s.env.Now()
+	s.env.ExecuteWorkflow("TestTaskTokenSuccessWorkflow", map[string]interface{}{"id": "example"})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(100*time.Second, s.env.Now().Sub(startTime))
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal("approved", result["review"])
+
+	parsed, err := ParseTaskToken(token)
+	if s.NoError(err) {
+		s.Equal("default-test-workflow-id", parsed.WorkflowID)
+		s.Equal("Example1", parsed.State)
+	}
+}
+
+func (s *UnitTestSuite) Test_Workflow_Task_Token_Failure() {
+	sm, err := FromJSON(taskTokenMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	var token string
+	registerTaskTokenHandler(s, &token)
+	RegisterWorkflow("TestTaskTokenFailureWorkflow", *sm)
+
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(TaskTokenSignal, TaskCallback{Token: token, Status: taskCallbackFailure, Error: "Rejected", Cause: "not approved"})
+	}, 10*time.Second)
+
+	s.env.ExecuteWorkflow("TestTaskTokenFailureWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	customErr, ok := s.env.GetWorkflowError().(*cadence.CustomError)
+	if s.True(ok) {
+		s.Equal("Rejected", customErr.Reason())
+	}
+}
+
+func (s *UnitTestSuite) Test_Workflow_Task_Token_Heartbeat_Timeout() {
+	sm, err := FromJSON(taskTokenMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	var token string
+	registerTaskTokenHandler(s, &token)
+	RegisterWorkflow("TestTaskTokenHeartbeatWorkflow", *sm)
+
+	startTime := s.env.Now()
+	s.env.ExecuteWorkflow("TestTaskTokenHeartbeatWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.Equal(60*time.Second, s.env.Now().Sub(startTime))
+
+	customErr, ok := s.env.GetWorkflowError().(*cadence.CustomError)
+	if s.True(ok) {
+		s.Equal(StatesTimeout, customErr.Reason())
+	}
+}
+
+var taskTokenParallelMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Parallel",
+			"Branches": [
+				{
+					"StartAt": "Review1",
+					"States": {
+						"Review1": {
+							"Type": "Task",
+							"Resource": "example:activity:RequestReview.waitForTaskToken",
+							"Parameters": {"branch": "1", "token.$": "$$.Task.Token"},
+							"ResultPath": "$.review",
+							"End": true
+						}
+					}
+				},
+				{
+					"StartAt": "Review2",
+					"States": {
+						"Review2": {
+							"Type": "Task",
+							"Resource": "example:activity:RequestReview.waitForTaskToken",
+							"Parameters": {"branch": "2", "token.$": "$$.Task.Token"},
+							"ResultPath": "$.review",
+							"End": true
+						}
+					}
+				}
+			],
+			"ResultPath": "$.reviews",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Task_Token_Concurrent() {
+	sm, err := FromJSON(taskTokenParallelMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	tokens := map[string]string{}
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		params := input.(map[string]interface{})
+		tokens[params["branch"].(string)] = params["token"].(string)
+		return nil, nil
+	}
+	RegisterHandler(handler)
+	RegisterWorkflow("TestTaskTokenConcurrentWorkflow", *sm)
+
+	// Callbacks arrive in the opposite order to the branches, with a heartbeat in between
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(TaskTokenSignal, TaskCallback{Token: tokens["2"], Status: taskCallbackSuccess, Output: "approved 2"})
+		s.env.SignalWorkflow(TaskTokenSignal, TaskCallback{Token: tokens["1"], Status: taskCallbackHeartbeat})
+		s.env.SignalWorkflow(TaskTokenSignal, TaskCallback{Token: tokens["1"], Status: taskCallbackSuccess, Output: "approved 1"})
+	}, 10*time.Second)
+
+	startTime := s.env.Now()
+	s.env.ExecuteWorkflow("TestTaskTokenConcurrentWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(10*time.Second, s.env.Now().Sub(startTime))
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+
+	reviews := result["reviews"].([]interface{})
+	if s.Len(reviews, 2) {
+		s.Equal("approved 1", reviews[0].(map[string]interface{})["review"])
+		s.Equal("approved 2", reviews[1].(map[string]interface{})["review"])
+	}
+}
+
+func TestParseTaskToken(t *testing.T) {
+	token := TaskToken{WorkflowID: "workflow", RunID: "run", State: "Example1", ID: "id"}
+
+	parsed, err := ParseTaskToken(token.String())
+	assert.NoError(t, err)
+	assert.Equal(t, token, *parsed)
+
+	_, err = ParseTaskToken("not a token")
+	assert.Error(t, err)
+
+	_, err = ParseTaskToken(TaskToken{RunID: "run"}.String())
+	assert.Error(t, err)
+}
@@ -0,0 +1,179 @@
+package aslworkflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/checkr/states-language-cadence/pkg/jsonpath"
+	"github.com/stretchr/testify/assert"
+
+	"go.uber.org/cadence"
+	"go.uber.org/cadence/workflow"
+)
+
+var machineTimeoutMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"TimeoutSeconds": 10,
+	"States": {
+		"Example1": {
+			"Type": "Wait",
+			"Seconds": 60,
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Machine_Timeout() {
+	sm, err := FromJSON(machineTimeoutMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestMachineTimeoutWorkflow", *sm)
+
+	startTime := s.env.Now()
+	s.env.ExecuteWorkflow("TestMachineTimeoutWorkflow", map[string]interface{}{})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.Equal(10*time.Second, s.env.Now().Sub(startTime))
+
+	err = s.env.GetWorkflowError()
+	if s.Error(err) {
+		customErr, ok := err.(*cadence.CustomError)
+		if s.True(ok) {
+			s.Equal(StatesTimeout, customErr.Reason())
+		}
+	}
+}
+
+var taskTimeoutMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "example:activity:TimeoutActivity",
+			"TimeoutSecondsPath": "$.timeout",
+			"Catch": [
+				{
+					"ErrorEquals": ["States.Timeout"],
+					"ResultPath": "$.error",
+					"Next": "TimedOut"
+				}
+			],
+			"End": true
+		},
+		"TimedOut": {
+			"Type": "Pass",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Task_Timeout() {
+	sm, err := FromJSON(taskTimeoutMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		return nil, workflow.NewHeartbeatTimeoutError()
+	}
+	RegisterHandler(handler)
+
+	RegisterWorkflow("TestTaskTimeoutWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestTaskTimeoutWorkflow", map[string]interface{}{"timeout": 1})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result map[string]interface{}
+	err = s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+
+	if s.Contains(result, "error") {
+		s.Equal(StatesTimeout, result["error"].(map[string]interface{})["Error"])
+	}
+}
+
+var taskTimeoutPathsMachine = []byte(`
+{
+	"StartAt": "Example1",
+	"States": {
+		"Example1": {
+			"Type": "Task",
+			"Resource": "example:activity:TimeoutActivity",
+			"InputPath": "$.task",
+			"Parameters": {
+				"timeout.$": "$.limits.timeout",
+				"heartbeat.$": "$.limits.heartbeat"
+			},
+			"TimeoutSecondsPath": "$.timeout",
+			"HeartbeatSecondsPath": "$.heartbeat",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Task_Timeout_Paths() {
+	sm, err := FromJSON(taskTimeoutPathsMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	var options []workflow.ActivityOptions
+	handler := func(ctx workflow.Context, resource string, input interface{}) (interface{}, error) {
+		options = append(options, getActivityOptions(ctx))
+		return nil, nil
+	}
+	RegisterHandler(handler)
+
+	RegisterWorkflow("TestTaskTimeoutPathsWorkflow", *sm)
+
+	s.env.ExecuteWorkflow("TestTaskTimeoutPathsWorkflow", map[string]interface{}{
+		"task": map[string]interface{}{
+			"limits": map[string]interface{}{"timeout": 30, "heartbeat": 10},
+		},
+	})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	// The paths are read from the Parameters output, not the raw input
+	if s.Len(options, 1) {
+		s.Equal(30*time.Second, options[0].StartToCloseTimeout)
+		s.Equal(10*time.Second, options[0].HeartbeatTimeout)
+	}
+}
+
+func TestTaskStateTimeouts(t *testing.T) {
+	path, err := jsonpath.NewPath("$.timeout")
+	assert.NoError(t, err)
+
+	state := &TaskState{TimeoutSecondsPath: path, HeartbeatSeconds: 5}
+
+	timeout, heartbeat, err := state.timeouts(nil, map[string]interface{}{"timeout": 30.0})
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, timeout)
+	assert.Equal(t, 5*time.Second, heartbeat)
+
+	_, _, err = state.timeouts(nil, map[string]interface{}{"timeout": -1.0})
+	assert.Error(t, err)
+
+	_, _, err = state.timeouts(nil, map[string]interface{}{})
+	assert.Error(t, err)
+
+	state = &TaskState{TimeoutSeconds: 10, HeartbeatSeconds: 10}
+	assert.Error(t, state.isTimeoutValid())
+
+	state = &TaskState{TimeoutSeconds: 10, TimeoutSecondsPath: path}
+	assert.Error(t, state.isTimeoutValid())
+}
@@ -2,6 +2,7 @@ package aslworkflow
 
 import (
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/checkr/states-language-cadence/pkg/jsonpath"
@@ -9,6 +10,34 @@ import (
 	"go.uber.org/cadence/workflow"
 )
 
+//
+// A Wait state with a Signal also ends early when the named Cadence signal is received. The signal
+// payload is written to the input at ResultPath. If the wait runs out first the state transitions to
+// TimeoutNext, or Next if it is not set.
+//
+//	"WaitForApproval": {
+//		"Type": "Wait",
+//		"Seconds": 259200,
+//		"Signal": "approval",
+//		"ResultPath": "$.approval",
+//		"Next": "Approved",
+//		"TimeoutNext": "Expired"
+//	}
+//
+// Cadence buffers signals until they are received, so a signal that arrives while no Wait is waiting for
+// it, e.g. a duplicate, ends the next Wait on that signal right away, even in a later iteration of a
+// loop. With a SignalCorrelationPath only signals whose payload has a CorrelationId equal to the value at
+// that path in the state's input end the Wait, others are received and dropped.
+//
+//	"WaitForApproval": {
+//		"Type": "Wait",
+//		"Seconds": 259200,
+//		"Signal": "approval",
+//		"SignalCorrelationPath": "$.requestId",
+//		"ResultPath": "$.approval",
+//		"Next": "Approved"
+//	}
+
 type WaitState struct {
 	stateStr // Include Defaults
 
@@ -24,6 +53,11 @@ type WaitState struct {
 	Timestamp     *time.Time     `json:",omitempty"`
 	TimestampPath *jsonpath.Path `json:",omitempty"`
 
+	Signal                *string        `json:",omitempty"`
+	SignalCorrelationPath *jsonpath.Path `json:",omitempty"`
+	ResultPath            *jsonpath.Path `json:",omitempty"`
+	TimeoutNext           *string        `json:",omitempty"`
+
 	Next *string `json:",omitempty"`
 	End  *bool   `json:",omitempty"`
 }
@@ -33,7 +67,7 @@ func (s *WaitState) process(ctx workflow.Context, input interface{}) (interface{
 
 	if s.SecondsPath != nil {
 		// Validate the path exists
-		secs, err := s.SecondsPath.GetNumber(input)
+		secs, err := s.SecondsPath.GetNumber(pathSource(ctx, s.SecondsPath, input))
 		if err != nil {
 			return nil, nil, err
 		}
@@ -44,7 +78,7 @@ func (s *WaitState) process(ctx workflow.Context, input interface{}) (interface{
 
 	} else if s.TimestampPath != nil {
 		// Validate the path exists
-		ts, err := s.TimestampPath.GetTime(input)
+		ts, err := s.TimestampPath.GetTime(pathSource(ctx, s.TimestampPath, input))
 		if err != nil {
 			return nil, nil, err
 		}
@@ -56,12 +90,79 @@ func (s *WaitState) process(ctx workflow.Context, input interface{}) (interface{
 		duration = s.Timestamp.Sub(now)
 	}
 
+	if s.Signal != nil {
+		var correlationID interface{}
+		if s.SignalCorrelationPath != nil {
+			id, err := getPath(ctx, s.SignalCorrelationPath, input)
+			if err != nil {
+				return nil, nil, fmt.Errorf("SignalCorrelationPath Error: %w", err)
+			}
+			correlationID = id
+		}
+		return s.waitForSignal(ctx, duration, correlationID)
+	}
+
 	err := workflow.Sleep(ctx, duration)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return input, nextState(s.Next, s.End), nil
+	// No result, so the input is passed through unchanged
+	return nil, nextState(s.Next, s.End), nil
+}
+
+// waitForSignal waits for the signal or the duration, whichever comes first. The signal payload is
+// returned as the result, the input is left unchanged if the wait times out. With a correlationID
+// signals for other IDs are dropped.
+func (s *WaitState) waitForSignal(ctx workflow.Context, duration time.Duration, correlationID interface{}) (interface{}, *string, error) {
+	timerCtx, cancelTimer := workflow.WithCancel(ctx)
+	defer cancelTimer()
+
+	var payload interface{}
+	var timerErr error
+	signaled, timedOut := false, false
+
+	selector := workflow.NewSelector(ctx)
+	selector.AddReceive(workflow.GetSignalChannel(ctx, *s.Signal), func(c workflow.Channel, more bool) {
+		payload = nil
+		c.Receive(ctx, &payload)
+		signaled = s.SignalCorrelationPath == nil || signalCorrelates(payload, correlationID)
+		if !signaled {
+			workflow.GetLogger(ctx).Info(fmt.Sprintf("WaitState(%v) dropped %v signal for another CorrelationId", *s.Name(), *s.Signal))
+		}
+	})
+	selector.AddFuture(workflow.NewTimer(timerCtx, duration), func(f workflow.Future) {
+		timerErr = f.Get(ctx, nil)
+		timedOut = true
+	})
+
+	for !signaled && !timedOut {
+		selector.Select(ctx)
+	}
+
+	if signaled {
+		return payload, nextState(s.Next, s.End), nil
+	}
+
+	if timerErr != nil {
+		return nil, nil, timerErr
+	}
+
+	if s.TimeoutNext != nil {
+		return nil, s.TimeoutNext, nil
+	}
+	return nil, nextState(s.Next, s.End), nil
+}
+
+// signalCorrelates returns true if the payload is an object with the CorrelationId
+func signalCorrelates(payload interface{}, correlationID interface{}) bool {
+	object, ok := payload.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	id, ok := object["CorrelationId"]
+	return ok && reflect.DeepEqual(id, correlationID)
 }
 
 func (s *WaitState) Execute(ctx workflow.Context, input interface{}) (output interface{}, next *string, err error) {
@@ -69,7 +170,7 @@ func (s *WaitState) Execute(ctx workflow.Context, input interface{}) (output int
 		processInputOutput(
 			s.InputPath,
 			s.OutputPath,
-			s.process,
+			processResult(s.ResultPath, s.process),
 		),
 	)(ctx, input)
 }
@@ -86,6 +187,26 @@ func (s *WaitState) Validate() error {
 		return fmt.Errorf("%v %v", errorPrefix(s), err)
 	}
 
+	if s.Signal == nil && (s.ResultPath != nil || s.TimeoutNext != nil || s.SignalCorrelationPath != nil) {
+		return fmt.Errorf("%v ResultPath, TimeoutNext and SignalCorrelationPath require Signal", errorPrefix(s))
+	}
+
+	if err := isReferencePathValid("SignalCorrelationPath", s.SignalCorrelationPath); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isResultPathValid("ResultPath", s.ResultPath); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isReferencePathValid("SecondsPath", s.SecondsPath); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
+	if err := isReferencePathValid("TimestampPath", s.TimestampPath); err != nil {
+		return fmt.Errorf("%v %w", errorPrefix(s), err)
+	}
+
 	exactlyOne := []bool{
 		s.Seconds != nil,
 		s.SecondsPath != nil,
@@ -2,7 +2,10 @@ package aslworkflow
 
 import (
 	"fmt"
+	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 var waitMachine = []byte(`
@@ -87,3 +90,165 @@ func (s *UnitTestSuite) Test_Workflow_Wait_State() {
 	s.Equal(float64(60), result["input_seconds"])
 	s.Equal(inputTime, result["input_timestamp"])
 }
+
+var waitSignalMachine = []byte(`
+{
+	"StartAt": "WaitForApproval",
+	"States": {
+		"WaitForApproval": {
+			"Type": "Wait",
+			"Seconds": 259200,
+			"Signal": "approval",
+			"ResultPath": "$.approval",
+			"Next": "Approved",
+			"TimeoutNext": "Expired"
+		},
+		"Approved": {
+			"Type": "Pass",
+			"Result": "approved",
+			"ResultPath": "$.status",
+			"End": true
+		},
+		"Expired": {
+			"Type": "Pass",
+			"Result": "expired",
+			"ResultPath": "$.status",
+			"End": true
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Wait_State_Signal() {
+	sm, err := FromJSON(waitSignalMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestWaitSignalWorkflow", *sm)
+
+	startTime := s.env.Now()
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("approval", map[string]interface{}{"approver": "example"})
+	}, time.Hour)
+
+	s.env.ExecuteWorkflow("TestWaitSignalWorkflow", map[string]interface{}{"id": "example"})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(time.Hour, s.env.Now().Sub(startTime))
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{
+		"id":       "example",
+		"approval": map[string]interface{}{"approver": "example"},
+		"status":   "approved",
+	}, result)
+}
+
+func (s *UnitTestSuite) Test_Workflow_Wait_State_Signal_Timeout() {
+	sm, err := FromJSON(waitSignalMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestWaitSignalTimeoutWorkflow", *sm)
+
+	startTime := s.env.Now()
+	s.env.ExecuteWorkflow("TestWaitSignalTimeoutWorkflow", map[string]interface{}{"id": "example"})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(72*time.Hour, s.env.Now().Sub(startTime))
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{"id": "example", "status": "expired"}, result)
+}
+
+var waitSignalLoopMachine = []byte(`
+{
+	"StartAt": "NextRound",
+	"States": {
+		"NextRound": {
+			"Type": "Pass",
+			"Parameters": {"round.$": "States.MathAdd($.round, 1)"},
+			"Next": "WaitForApproval"
+		},
+		"WaitForApproval": {
+			"Type": "Wait",
+			"Seconds": 3600,
+			"Signal": "approval",
+			"SignalCorrelationPath": "$.round",
+			"ResultPath": "$.approval",
+			"Next": "MoreRounds"
+		},
+		"MoreRounds": {
+			"Type": "Choice",
+			"Choices": [
+				{"Variable": "$.round", "NumericLessThan": 2, "Next": "NextRound"}
+			],
+			"Default": "Done"
+		},
+		"Done": {
+			"Type": "Succeed"
+		}
+	}
+}
+`)
+
+func (s *UnitTestSuite) Test_Workflow_Wait_State_Signal_Loop() {
+	sm, err := FromJSON(waitSignalLoopMachine)
+	if err != nil {
+		s.NoError(err)
+		return
+	}
+
+	RegisterWorkflow("TestWaitSignalLoopWorkflow", *sm)
+
+	// The duplicate for round 1 is still buffered when round 2 starts waiting
+	startTime := s.env.Now()
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("approval", map[string]interface{}{"CorrelationId": 1, "approver": "first"})
+		s.env.SignalWorkflow("approval", map[string]interface{}{"CorrelationId": 1, "approver": "duplicate"})
+	}, time.Minute)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("approval", map[string]interface{}{"CorrelationId": 2, "approver": "second"})
+	}, time.Hour/2)
+
+	s.env.ExecuteWorkflow("TestWaitSignalLoopWorkflow", map[string]interface{}{"round": 0})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(time.Hour/2, s.env.Now().Sub(startTime))
+
+	var result map[string]interface{}
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(map[string]interface{}{
+		"round":    2.0,
+		"approval": map[string]interface{}{"CorrelationId": 2.0, "approver": "second"},
+	}, result)
+}
+
+func TestWaitStateSignalValidate(t *testing.T) {
+	_, err := FromJSON([]byte(`{
+		"StartAt": "Example1",
+		"States": {"Example1": {"Type": "Wait", "Seconds": 10, "TimeoutNext": "Example1", "End": true}}
+	}`))
+	assert.Error(t, err)
+
+	_, err = FromJSON([]byte(`{
+		"StartAt": "Example1",
+		"States": {"Example1": {"Type": "Wait", "Seconds": 10, "Signal": "example", "TimeoutNext": "Missing", "End": true}}
+	}`))
+	assert.Error(t, err)
+
+	_, err = FromJSON([]byte(`{
+		"StartAt": "Example1",
+		"States": {"Example1": {"Type": "Wait", "Seconds": 10, "SignalCorrelationPath": "$.id", "End": true}}
+	}`))
+	assert.Error(t, err)
+}
@@ -2,21 +2,25 @@ package aslworkflow
 
 import (
 	"fmt"
-	"time"
 
 	"go.uber.org/cadence/workflow"
 )
 
 func Workflow(ctx workflow.Context, sm StateMachine, input interface{}) (interface{}, error) {
-	ao := workflow.ActivityOptions{
-		ScheduleToStartTimeout: time.Minute,
-		StartToCloseTimeout:    time.Minute,
-		HeartbeatTimeout:       time.Second * 20,
+	ctx = withActivityOptions(ctx, defaultActivityOptions())
+	ctx = withExecution(ctx, input)
+	ctx = withRegistry(ctx, sm.registry)
+	ctx = withTaskCallbacks(ctx)
+
+	if err := setProgressQuery(ctx); err != nil {
+		return nil, err
 	}
-	ctx = workflow.WithActivityOptions(ctx, ao)
 
 	output, err := sm.Execute(ctx, input)
-	return output, err
+	if err != nil {
+		return nil, workflowError(err)
+	}
+	return output, nil
 }
 
 func RegisterWorkflow(workflowName string, initStateMachine StateMachine) {
@@ -33,7 +37,21 @@ func RegisterWorkflow(workflowName string, initStateMachine StateMachine) {
 			return nil, fmt.Errorf("failed to initialize the state machine: %w", err)
 		}
 
+		// The registry holds handlers so it cannot be recorded, it always comes from the worker
+		sm.registry = initStateMachine.registry
+
 		return Workflow(ctx, sm, input)
 	}
 	workflow.RegisterWithOptions(workflowFunc, workflow.RegisterOptions{Name: workflowName})
 }
+
+// RegisterWorkflowWithRegistry registers a workflow for the machine that routes Task resources with the
+// registry, other workflows registered from the same machine are not affected
+func RegisterWorkflowWithRegistry(workflowName string, sm StateMachine, registry *ResourceRegistry) error {
+	if err := sm.SetRegistry(registry); err != nil {
+		return err
+	}
+
+	RegisterWorkflow(workflowName, sm)
+	return nil
+}
@@ -25,17 +25,41 @@ nil for JSON null
 var ErrNotFoundError = errors.New("Not Found")
 
 type Path struct {
-	path []string
+	raw      string
+	segments []segment
+	context  bool
 }
 
 // NewPath takes string returns JSONPath Object
 func NewPath(pathString string) (*Path, error) {
 	path := Path{}
-	pathArray, err := ParsePathString(pathString)
-	path.path = pathArray
+	err := path.parse(pathString)
 	return &path, err
 }
 
+// parse sets the path from a string, paths starting with $$ refer to the context object
+func (path *Path) parse(pathString string) error {
+	// must start with $<value> otherwise empty path
+	if pathString == "" || pathString[0:1] != "$" {
+		return fmt.Errorf("Bad JSON path: must start with $")
+	}
+
+	path.raw = pathString
+	path.context = strings.HasPrefix(pathString, "$$")
+	if path.context {
+		pathString = pathString[1:]
+	}
+
+	segments, err := parseSegments(pathString)
+	path.segments = segments
+	return err
+}
+
+// IsContext returns true if the path starts with $$ and should be applied to the context object instead of the input
+func (path *Path) IsContext() bool {
+	return path != nil && path.context
+}
+
 // UnmarshalJSON makes a path out of a json string
 func (path *Path) UnmarshalJSON(b []byte) error {
 	var pathString string
@@ -45,56 +69,43 @@ func (path *Path) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	pathArray, err := ParsePathString(pathString)
-
-	if err != nil {
-		return err
-	}
-
-	path.path = pathArray
-	return nil
+	return path.parse(pathString)
 }
 
 // MarshalJSON converts path to json string
 func (path *Path) MarshalJSON() ([]byte, error) {
-	if len(path.path) == 0 {
-		return json.Marshal("$")
-	}
 	return json.Marshal(path.String())
 }
 
+// String returns the path as it was originally written
 func (path *Path) String() string {
-	return fmt.Sprintf("$.%v", strings.Join(path.path[:], "."))
+	if path.raw == "" {
+		return "$"
+	}
+	return path.raw
 }
 
-// ParsePathString parses a path string
+// ParsePathString parses a path string into its member names
+//
+// Deprecated: only supports paths of member names, use NewPath for the full syntax
 func ParsePathString(pathString string) ([]string, error) {
-	// must start with $.<value> otherwise empty path
-	if pathString == "" || pathString[0:1] != "$" {
-		return nil, fmt.Errorf("Bad JSON path: must start with $")
+	if strings.HasPrefix(pathString, "$$") {
+		return nil, fmt.Errorf("Bad JSON path: context paths are not supported")
 	}
 
-	if pathString == "$" {
-		// Default is no path
-		return []string{}, nil
-	}
-
-	if len(pathString) < 2 {
-		// This handles the case for $. or $* which are invalid
-		return nil, fmt.Errorf("Bad JSON path: cannot not be 2 characters")
+	path, err := NewPath(pathString)
+	if err != nil {
+		return nil, err
 	}
 
-	head := pathString[2:]
-	pathArray := strings.Split(head, ".")
-
-	// if path contains an "" error
-	for _, p := range pathArray {
-		if p == "" {
-			return nil, fmt.Errorf("Bad JSON path: has empty element")
+	names := []string{}
+	for _, s := range path.segments {
+		if s.kind != memberSegment || s.recursive {
+			return nil, fmt.Errorf("Bad JSON path: only member names are supported")
 		}
+		names = append(names, s.key)
 	}
-	// Simple Path Builder
-	return pathArray, nil
+	return names, nil
 }
 
 // PUBLIC METHODS
@@ -198,21 +209,27 @@ func (path *Path) GetMap(input interface{}) (output map[string]interface{}, err
 	return output, nil
 }
 
-// Get returns interface from Path
+// Get returns interface from Path, query paths return an array of all the values they select
 func (path *Path) Get(input interface{}) (value interface{}, err error) {
 	if path == nil {
 		return input, nil // Default is $
 	}
-	return recursiveGet(input, path.path)
+
+	if !path.IsReference() {
+		return path.Query(input), nil
+	}
+	return recursiveGet(input, path.segments)
 }
 
 // Set sets a Value in a map with Path
 func (path *Path) Set(input interface{}, value interface{}) (output interface{}, err error) {
-	var setPath []string
-	if path == nil {
-		setPath = []string{} // default "$"
-	} else {
-		setPath = path.path
+	var setPath []segment
+	if path != nil {
+		setPath = path.segments
+	}
+
+	if !path.IsReference() {
+		return nil, fmt.Errorf("Cannot Set value with query path %v", path)
 	}
 
 	if len(setPath) == 0 {
@@ -228,12 +245,45 @@ func (path *Path) Set(input interface{}, value interface{}) (output interface{},
 			return nil, fmt.Errorf("Cannot Set value %q type %q in root JSON path $", value, reflect.TypeOf(value))
 		}
 	}
-	return recursiveSet(input, value, setPath), nil
+	return recursiveSet(input, value, setPath)
 }
 
 // PRIVATE METHODS
 
-func recursiveSet(data interface{}, value interface{}, path []string) (output interface{}) {
+func recursiveSet(data interface{}, value interface{}, path []segment) (output interface{}, err error) {
+	setChild := func(child interface{}) (interface{}, error) {
+		if len(path) == 1 {
+			return value, nil
+		}
+		return recursiveSet(child, value, path[1:])
+	}
+
+	if path[0].kind == indexSegment {
+		// Overwrite current data with new array if it is not one
+		dataArray, _ := data.([]interface{})
+
+		index := path[0].index
+		if index < 0 {
+			index += len(dataArray)
+			if index < 0 {
+				return nil, fmt.Errorf("Cannot Set index %d out of range", path[0].index)
+			}
+		}
+
+		// Grow the array to fit the index
+		for len(dataArray) <= index {
+			dataArray = append(dataArray, nil)
+		}
+
+		newValue, err := setChild(dataArray[index])
+		if err != nil {
+			return nil, err
+		}
+		dataArray[index] = newValue
+
+		return dataArray, nil
+	}
+
 	var dataMap map[string]interface{}
 
 	switch dataCast := data.(type) {
@@ -245,16 +295,16 @@ func recursiveSet(data interface{}, value interface{}, path []string) (output in
 		dataMap = make(map[string]interface{})
 	}
 
-	if len(path) == 1 {
-		dataMap[path[0]] = value
-	} else {
-		dataMap[path[0]] = recursiveSet(dataMap[path[0]], value, path[1:])
+	newValue, err := setChild(dataMap[path[0].key])
+	if err != nil {
+		return nil, err
 	}
+	dataMap[path[0].key] = newValue
 
-	return dataMap
+	return dataMap, nil
 }
 
-func recursiveGet(data interface{}, path []string) (interface{}, error) {
+func recursiveGet(data interface{}, path []segment) (interface{}, error) {
 	if len(path) == 0 {
 		return data, nil
 	}
@@ -265,7 +315,11 @@ func recursiveGet(data interface{}, path []string) (interface{}, error) {
 
 	switch dataCast := data.(type) {
 	case map[string]interface{}:
-		value, ok := dataCast[path[0]]
+		if path[0].kind != memberSegment {
+			return data, ErrNotFoundError
+		}
+
+		value, ok := dataCast[path[0].key]
 
 		if !ok {
 			return data, ErrNotFoundError
@@ -273,6 +327,22 @@ func recursiveGet(data interface{}, path []string) (interface{}, error) {
 
 		return recursiveGet(value, path[1:])
 
+	case []interface{}:
+		if path[0].kind != indexSegment {
+			return data, ErrNotFoundError
+		}
+
+		index := path[0].index
+		if index < 0 {
+			index += len(dataCast)
+		}
+
+		if index < 0 || index >= len(dataCast) {
+			return data, ErrNotFoundError
+		}
+
+		return recursiveGet(dataCast[index], path[1:])
+
 	default:
 		return data, ErrNotFoundError
 	}
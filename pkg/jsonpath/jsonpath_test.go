@@ -0,0 +1,190 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathGet(t *testing.T) {
+	var input interface{}
+	err := json.Unmarshal([]byte(`{
+		"a": {"b": "c"},
+		"items": [{"id": 1}, {"id": 2}, {"id": 3}],
+		"key with.dot": "dotted",
+		"it's": "quoted",
+		"matrix": [[1, 2], [3, 4]]
+	}`), &input)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		path     string
+		expected interface{}
+	}{
+		{"$", input},
+		{"$.a.b", "c"},
+		{"$['a']['b']", "c"},
+		{`$["a"].b`, "c"},
+		{"$.items[0].id", float64(1)},
+		{"$.items[-1].id", float64(3)},
+		{"$['key with.dot']", "dotted"},
+		{`$['it\'s']`, "quoted"},
+		{"$.matrix[1][0]", float64(3)},
+	}
+
+	for _, test := range tests {
+		path, err := NewPath(test.path)
+		if !assert.NoError(t, err, test.path) {
+			continue
+		}
+
+		output, err := path.Get(input)
+		assert.NoError(t, err, test.path)
+		assert.Equal(t, test.expected, output, test.path)
+	}
+
+	for _, missing := range []string{"$.missing", "$.items[3]", "$.items[-4]", "$.a[0]", "$.items.id"} {
+		path, err := NewPath(missing)
+		if !assert.NoError(t, err, missing) {
+			continue
+		}
+
+		_, err = path.Get(input)
+		assert.Error(t, err, missing)
+	}
+}
+
+func TestPathSet(t *testing.T) {
+	path, err := NewPath("$.items[1].id")
+	assert.NoError(t, err)
+
+	output, err := path.Set(map[string]interface{}{}, "x")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"items": []interface{}{nil, map[string]interface{}{"id": "x"}},
+	}, output)
+
+	path, err = NewPath("$.items[-1]")
+	assert.NoError(t, err)
+
+	output, err = path.Set(map[string]interface{}{"items": []interface{}{"a", "b"}}, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"items": []interface{}{"a", "c"}}, output)
+
+	_, err = path.Set(map[string]interface{}{}, "c")
+	assert.Error(t, err)
+
+	path, err = NewPath("$['key with.dot']")
+	assert.NoError(t, err)
+
+	output, err = path.Set(nil, "dotted")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"key with.dot": "dotted"}, output)
+}
+
+func TestPathParse(t *testing.T) {
+	for _, valid := range []string{"$", "$$", "$.a", "$$.Execution.Id", "$.a[0]", "$['a b']", "$.a[-1].b"} {
+		path, err := NewPath(valid)
+		if assert.NoError(t, err, valid) {
+			// String and MarshalJSON return the original form
+			assert.Equal(t, valid, path.String())
+
+			raw, err := json.Marshal(path)
+			assert.NoError(t, err)
+
+			var roundTrip Path
+			assert.NoError(t, json.Unmarshal(raw, &roundTrip))
+			assert.Equal(t, valid, roundTrip.String())
+		}
+	}
+
+	for _, invalid := range []string{"", "a", "$.", "$..", "$a", "$.a[", "$.a[b]", "$['a]", "$.a.", "$[0"} {
+		_, err := NewPath(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParsePathString(t *testing.T) {
+	names, err := ParsePathString("$")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, names)
+
+	names, err = ParsePathString("$.a['b c'].d")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b c", "d"}, names)
+
+	for _, invalid := range []string{"", "a", "$.", "$.a.", "$.a[0]", "$..a", "$.a[*]", "$$.Execution.Id"} {
+		_, err := ParsePathString(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPathQuery(t *testing.T) {
+	var input interface{}
+	err := json.Unmarshal([]byte(`{
+		"items": [
+			{"id": 1, "name": "a", "price": 5, "tags": ["x"]},
+			{"id": 2, "name": "b", "price": 15, "category": "books"},
+			{"id": 3, "name": "c", "price": 25, "category": "toys"}
+		],
+		"other": {"id": 4, "nested": {"id": 5}},
+		"limit": 20
+	}`), &input)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		path     string
+		expected []interface{}
+	}{
+		{"$.items[*].name", []interface{}{"a", "b", "c"}},
+		{"$.items.*.id", []interface{}{float64(1), float64(2), float64(3)}},
+		{"$..id", []interface{}{float64(1), float64(2), float64(3), float64(4), float64(5)}},
+		{"$.other..id", []interface{}{float64(4), float64(5)}},
+		{"$.items[0:2].id", []interface{}{float64(1), float64(2)}},
+		{"$.items[1:].id", []interface{}{float64(2), float64(3)}},
+		{"$.items[-2:].id", []interface{}{float64(2), float64(3)}},
+		{"$.items[::-1].id", []interface{}{float64(3), float64(2), float64(1)}},
+		{"$.items[0,2].id", []interface{}{float64(1), float64(3)}},
+		{"$.other['id','missing']", []interface{}{float64(4)}},
+		{"$.items[?(@.price > 10)].id", []interface{}{float64(2), float64(3)}},
+		{"$.items[?(@.price >= 5 && @.category == 'books')].id", []interface{}{float64(2)}},
+		{"$.items[?(@.category == 'toys' || @.price < 10)].id", []interface{}{float64(1), float64(3)}},
+		{"$.items[?(@.tags)].id", []interface{}{float64(1)}},
+		{"$.items[?(!@.category)].id", []interface{}{float64(1)}},
+		{"$.items[?(@.price < $.limit)].id", []interface{}{float64(1), float64(2)}},
+		{"$.items[?(@.price > 100)].id", []interface{}{}},
+	}
+
+	for _, test := range tests {
+		path, err := NewPath(test.path)
+		if !assert.NoError(t, err, test.path) {
+			continue
+		}
+		assert.False(t, path.IsReference(), test.path)
+
+		output, err := path.Get(input)
+		assert.NoError(t, err, test.path)
+		assert.Equal(t, test.expected, output, test.path)
+	}
+
+	for _, invalid := range []string{"$.items[?(@.price >)]", "$.items[?(@.price > 10]", "$.items[1:2:0]", "$.items[?(5)]"} {
+		_, err := NewPath(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPathReference(t *testing.T) {
+	for _, reference := range []string{"$", "$.a", "$.a[0]", "$['a'][-1]"} {
+		path, err := NewPath(reference)
+		if assert.NoError(t, err, reference) {
+			assert.True(t, path.IsReference(), reference)
+		}
+	}
+
+	path, err := NewPath("$.items[*]")
+	assert.NoError(t, err)
+
+	_, err = path.Set(map[string]interface{}{}, "x")
+	assert.Error(t, err)
+}
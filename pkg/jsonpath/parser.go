@@ -0,0 +1,339 @@
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+Paths are tokenized into segments. Reference paths only contain segments that select a single child
+of the current value:
+
+$.store.books        member names in dot notation
+$['key with.dot']    quoted member names in bracket notation, \' and \\ are escapes
+$.books[0]           array indexes
+$.books[-1]          negative indexes count back from the end of the array
+
+Query paths can also contain segments that select any number of values, see query.go:
+
+$.books[*].title     wildcards
+$..title             recursive descent
+$.books[0:5:2]       array slices
+$.books[0,2]         unions of indexes or quoted member names
+$.books[?(@.price > 10)]  filters
+
+*/
+
+type segmentKind int
+
+const (
+	memberSegment segmentKind = iota
+	indexSegment
+	wildcardSegment
+	sliceSegment
+	unionSegment
+	filterSegment
+)
+
+type segment struct {
+	kind  segmentKind
+	key   string
+	index int
+
+	// recursive segments are applied to the current value and all of its descendants
+	recursive bool
+
+	slice  *sliceRange
+	union  []segment
+	filter filterExpr
+}
+
+type sliceRange struct {
+	start *int
+	end   *int
+	step  int
+}
+
+// isReference returns true if the segment always selects at most one value
+func (s segment) isReference() bool {
+	return !s.recursive && (s.kind == memberSegment || s.kind == indexSegment)
+}
+
+type pathParser struct {
+	input string
+	pos   int
+}
+
+// parseSegments tokenizes everything after the leading $ of a path
+func parseSegments(pathString string) ([]segment, error) {
+	p := &pathParser{input: pathString, pos: 1}
+	segments, err := p.parseSegments()
+	if err != nil {
+		return nil, fmt.Errorf("Bad JSON path: %v", err)
+	}
+	return segments, nil
+}
+
+func (p *pathParser) parseSegments() ([]segment, error) {
+	segments := []segment{}
+
+	for !p.done() {
+		var s segment
+		var err error
+
+		switch p.peek() {
+		case '.':
+			s, err = p.parseDot()
+		case '[':
+			s, err = p.parseBracket()
+		default:
+			err = fmt.Errorf("unexpected %q at position %d", p.peek(), p.pos)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	}
+
+	return segments, nil
+}
+
+func (p *pathParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *pathParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *pathParser) skipSpaces() {
+	for !p.done() && p.peek() == ' ' {
+		p.pos++
+	}
+}
+
+func (p *pathParser) parseDot() (segment, error) {
+	p.pos++ // .
+
+	recursive := false
+	if p.peek() == '.' {
+		p.pos++
+		recursive = true
+
+		if p.peek() == '[' {
+			s, err := p.parseBracket()
+			s.recursive = true
+			return s, err
+		}
+	}
+
+	if p.peek() == '*' {
+		p.pos++
+		return segment{kind: wildcardSegment, recursive: recursive}, nil
+	}
+
+	start := p.pos
+	for !p.done() && p.peek() != '.' && p.peek() != '[' {
+		p.pos++
+	}
+
+	name := p.input[start:p.pos]
+	if name == "" {
+		return segment{}, fmt.Errorf("has empty element")
+	}
+
+	return segment{kind: memberSegment, key: name, recursive: recursive}, nil
+}
+
+func (p *pathParser) parseBracket() (segment, error) {
+	p.pos++ // [
+	p.skipSpaces()
+
+	var s segment
+	var err error
+
+	switch {
+	case p.peek() == '*':
+		p.pos++
+		s = segment{kind: wildcardSegment}
+	case strings.HasPrefix(p.input[p.pos:], "?("):
+		s, err = p.parseFilter()
+	default:
+		s, err = p.parseUnion()
+	}
+
+	if err != nil {
+		return segment{}, err
+	}
+
+	p.skipSpaces()
+	if p.peek() != ']' {
+		return segment{}, fmt.Errorf("missing ] at position %d", p.pos)
+	}
+	p.pos++ // ]
+
+	return s, nil
+}
+
+// parseUnion reads a comma separated list of quoted names, indexes, or a single slice
+func (p *pathParser) parseUnion() (segment, error) {
+	var union []segment
+
+	for {
+		p.skipSpaces()
+
+		var s segment
+		switch p.peek() {
+		case '\'', '"':
+			name, err := p.parseQuoted()
+			if err != nil {
+				return segment{}, err
+			}
+			s = segment{kind: memberSegment, key: name}
+		default:
+			start := p.pos
+			for !p.done() && p.peek() != ']' && p.peek() != ',' {
+				p.pos++
+			}
+
+			token := strings.TrimSpace(p.input[start:p.pos])
+			if strings.Contains(token, ":") {
+				slice, err := parseSlice(token)
+				if err != nil {
+					return segment{}, err
+				}
+				s = segment{kind: sliceSegment, slice: slice}
+				break
+			}
+
+			index, err := strconv.Atoi(token)
+			if err != nil {
+				return segment{}, fmt.Errorf("bad array index %q", token)
+			}
+			s = segment{kind: indexSegment, index: index}
+		}
+
+		union = append(union, s)
+
+		p.skipSpaces()
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++ // ,
+	}
+
+	if len(union) == 1 {
+		return union[0], nil
+	}
+
+	for _, s := range union {
+		if s.kind == sliceSegment {
+			return segment{}, fmt.Errorf("slices cannot be used in a union")
+		}
+	}
+
+	return segment{kind: unionSegment, union: union}, nil
+}
+
+// parseSlice reads start:end:step where every part is optional
+func parseSlice(token string) (*sliceRange, error) {
+	parts := strings.Split(token, ":")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("bad slice %q", token)
+	}
+
+	bounds := make([]*int, 3)
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("bad slice %q", token)
+		}
+		bounds[i] = &n
+	}
+
+	slice := &sliceRange{start: bounds[0], end: bounds[1], step: 1}
+	if bounds[2] != nil {
+		slice.step = *bounds[2]
+	}
+
+	if slice.step == 0 {
+		return nil, fmt.Errorf("slice step cannot be 0")
+	}
+
+	return slice, nil
+}
+
+func (p *pathParser) parseFilter() (segment, error) {
+	p.pos += len("?(")
+
+	start := p.pos
+	depth := 1
+	var quote byte
+
+	for !p.done() && depth > 0 {
+		c := p.peek()
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				p.pos++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		}
+		p.pos++
+	}
+
+	if depth != 0 {
+		return segment{}, fmt.Errorf("unterminated filter")
+	}
+
+	filter, err := parseFilterExpr(p.input[start : p.pos-1])
+	if err != nil {
+		return segment{}, err
+	}
+
+	return segment{kind: filterSegment, filter: filter}, nil
+}
+
+// parseQuoted reads a quoted member name, the closing quote must match the opening one
+func (p *pathParser) parseQuoted() (string, error) {
+	quote := p.peek()
+	p.pos++
+
+	var sb strings.Builder
+	for !p.done() {
+		c := p.input[p.pos]
+		p.pos++
+
+		switch c {
+		case '\\':
+			if p.done() {
+				return "", fmt.Errorf("unterminated escape")
+			}
+			sb.WriteByte(p.input[p.pos])
+			p.pos++
+		case quote:
+			return sb.String(), nil
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return "", fmt.Errorf("unterminated quoted name")
+}
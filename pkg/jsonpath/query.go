@@ -0,0 +1,496 @@
+package jsonpath
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Query paths select any number of values and always return an array of the results, in document
+order. Object members are visited in sorted key order so results are deterministic.
+
+Filters support comparisons (==, !=, <, <=, >, >=) between paths relative to the current item (@),
+paths from the root ($) and literals (numbers, quoted strings, true, false, null). Comparisons can be
+combined with &&, || and !, and a path on its own tests that the value exists.
+
+$.items[?(@.price > 10 && @.category == 'books')]
+
+*/
+
+// IsReference returns true if the path can only select a single value, e.g. $.a.b[0]
+func (path *Path) IsReference() bool {
+	if path == nil {
+		return true
+	}
+
+	for _, s := range path.segments {
+		if !s.isReference() {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns an array of every value selected by the path
+func (path *Path) Query(input interface{}) []interface{} {
+	if path == nil {
+		return []interface{}{input}
+	}
+	return query(input, input, path.segments)
+}
+
+func query(root interface{}, input interface{}, segments []segment) []interface{} {
+	nodes := []interface{}{input}
+
+	for _, s := range segments {
+		next := []interface{}{}
+		for _, node := range nodes {
+			if s.recursive {
+				for _, d := range descendants(node) {
+					next = append(next, s.apply(root, d)...)
+				}
+			} else {
+				next = append(next, s.apply(root, node)...)
+			}
+		}
+		nodes = next
+	}
+
+	return nodes
+}
+
+// descendants returns the value and every value nested in it
+func descendants(data interface{}) []interface{} {
+	output := []interface{}{data}
+	for _, child := range children(data) {
+		output = append(output, descendants(child)...)
+	}
+	return output
+}
+
+// children returns the elements of an array or the member values of an object in key order
+func children(data interface{}) []interface{} {
+	switch dataCast := data.(type) {
+	case []interface{}:
+		return dataCast
+	case map[string]interface{}:
+		keys := make([]string, 0, len(dataCast))
+		for key := range dataCast {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		output := make([]interface{}, len(keys))
+		for i, key := range keys {
+			output[i] = dataCast[key]
+		}
+		return output
+	}
+	return nil
+}
+
+// apply returns every value the segment selects from data
+func (s segment) apply(root interface{}, data interface{}) []interface{} {
+	switch s.kind {
+	case memberSegment, indexSegment:
+		value, err := recursiveGet(data, []segment{{kind: s.kind, key: s.key, index: s.index}})
+		if err != nil {
+			return nil
+		}
+		return []interface{}{value}
+
+	case wildcardSegment:
+		return children(data)
+
+	case unionSegment:
+		var output []interface{}
+		for _, u := range s.union {
+			output = append(output, u.apply(root, data)...)
+		}
+		return output
+
+	case sliceSegment:
+		array, ok := data.([]interface{})
+		if !ok {
+			return nil
+		}
+		return s.slice.apply(array)
+
+	case filterSegment:
+		var output []interface{}
+		for _, child := range children(data) {
+			if s.filter.match(root, child) {
+				output = append(output, child)
+			}
+		}
+		return output
+	}
+
+	return nil
+}
+
+func (r *sliceRange) apply(array []interface{}) []interface{} {
+	length := len(array)
+
+	bound := func(n *int, def int) int {
+		if n == nil {
+			return def
+		}
+		i := *n
+		if i < 0 {
+			i += length
+		}
+		if i < 0 {
+			return -1
+		}
+		if i > length {
+			return length
+		}
+		return i
+	}
+
+	output := []interface{}{}
+	if r.step > 0 {
+		start, end := bound(r.start, 0), bound(r.end, length)
+		if start < 0 {
+			start = 0
+		}
+		for i := start; i < end; i += r.step {
+			output = append(output, array[i])
+		}
+	} else {
+		start, end := bound(r.start, length-1), bound(r.end, -1)
+		if start >= length {
+			start = length - 1
+		}
+		for i := start; i > end && i >= 0; i += r.step {
+			output = append(output, array[i])
+		}
+	}
+	return output
+}
+
+//////
+// Filters
+//////
+
+type filterExpr interface {
+	match(root interface{}, item interface{}) bool
+}
+
+type orExpr []filterExpr
+
+func (e orExpr) match(root interface{}, item interface{}) bool {
+	for _, sub := range e {
+		if sub.match(root, item) {
+			return true
+		}
+	}
+	return false
+}
+
+type andExpr []filterExpr
+
+func (e andExpr) match(root interface{}, item interface{}) bool {
+	for _, sub := range e {
+		if !sub.match(root, item) {
+			return false
+		}
+	}
+	return true
+}
+
+type notExpr struct {
+	expr filterExpr
+}
+
+func (e notExpr) match(root interface{}, item interface{}) bool {
+	return !e.expr.match(root, item)
+}
+
+// existsExpr matches if the path selects a value
+type existsExpr struct {
+	operand filterOperand
+}
+
+func (e existsExpr) match(root interface{}, item interface{}) bool {
+	_, ok := e.operand.value(root, item)
+	return ok
+}
+
+type comparisonExpr struct {
+	left  filterOperand
+	op    string
+	right filterOperand
+}
+
+func (e comparisonExpr) match(root interface{}, item interface{}) bool {
+	left, ok := e.left.value(root, item)
+	if !ok {
+		return false
+	}
+
+	right, ok := e.right.value(root, item)
+	if !ok {
+		return false
+	}
+
+	switch e.op {
+	case "==":
+		return reflect.DeepEqual(left, right)
+	case "!=":
+		return !reflect.DeepEqual(left, right)
+	}
+
+	// Ordering is only defined between two numbers or two strings
+	switch leftCast := left.(type) {
+	case float64:
+		rightCast, ok := right.(float64)
+		return ok && compareOrdered(e.op, leftCast < rightCast, leftCast == rightCast)
+	case string:
+		rightCast, ok := right.(string)
+		return ok && compareOrdered(e.op, leftCast < rightCast, leftCast == rightCast)
+	}
+
+	return false
+}
+
+func compareOrdered(op string, less bool, equal bool) bool {
+	switch op {
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	case ">=":
+		return !less
+	}
+	return false
+}
+
+// filterOperand is either a literal or a path from the current item (@) or the root ($)
+type filterOperand struct {
+	literal  interface{}
+	segments []segment
+	relative bool
+	isPath   bool
+}
+
+func (o filterOperand) value(root interface{}, item interface{}) (interface{}, bool) {
+	if !o.isPath {
+		return o.literal, true
+	}
+
+	data := root
+	if o.relative {
+		data = item
+	}
+
+	value, err := recursiveGet(data, o.segments)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+type filterParser struct {
+	input string
+	pos   int
+}
+
+func parseFilterExpr(input string) (filterExpr, error) {
+	p := &filterParser{input: input}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("bad filter %q: %v", input, err)
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("bad filter %q: unexpected %q", input, p.input[p.pos:])
+	}
+
+	return expr, nil
+}
+
+func (p *filterParser) skipSpaces() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *filterParser) consume(token string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(p.input[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	var or orExpr
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, expr)
+
+		if !p.consume("||") {
+			break
+		}
+	}
+
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	var and andExpr
+	for {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, expr)
+
+		if !p.consume("&&") {
+			break
+		}
+	}
+
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	if p.consume("!") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr: expr}, nil
+	}
+
+	if p.consume("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return expr, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return comparisonExpr{left: left, op: op, right: right}, nil
+		}
+	}
+
+	if !left.isPath {
+		return nil, fmt.Errorf("literal %v without comparison", left.literal)
+	}
+	return existsExpr{operand: left}, nil
+}
+
+func (p *filterParser) parseOperand() (filterOperand, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return filterOperand{}, fmt.Errorf("missing operand")
+	}
+
+	switch c := p.input[p.pos]; c {
+	case '@', '$':
+		token := p.scanPath()
+		segments, err := (&pathParser{input: token, pos: 1}).parseSegments()
+		if err != nil {
+			return filterOperand{}, err
+		}
+		for _, s := range segments {
+			if !s.isReference() {
+				return filterOperand{}, fmt.Errorf("filter paths must be reference paths")
+			}
+		}
+		return filterOperand{segments: segments, relative: c == '@', isPath: true}, nil
+
+	case '\'', '"':
+		pp := &pathParser{input: p.input, pos: p.pos}
+		str, err := pp.parseQuoted()
+		if err != nil {
+			return filterOperand{}, err
+		}
+		p.pos = pp.pos
+		return filterOperand{literal: str}, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune(" =!<>&|()", rune(p.input[p.pos])) {
+		p.pos++
+	}
+
+	token := p.input[start:p.pos]
+	switch token {
+	case "true":
+		return filterOperand{literal: true}, nil
+	case "false":
+		return filterOperand{literal: false}, nil
+	case "null":
+		return filterOperand{literal: nil}, nil
+	}
+
+	number, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return filterOperand{}, fmt.Errorf("bad operand %q", token)
+	}
+	return filterOperand{literal: number}, nil
+}
+
+// scanPath reads a path operand up to the next operator, skipping over brackets and quotes
+func (p *filterParser) scanPath() string {
+	start := p.pos
+	depth := 0
+	var quote byte
+
+	for ; p.pos < len(p.input); p.pos++ {
+		c := p.input[p.pos]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				p.pos++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0 && strings.ContainsRune(" =!<>&|()", rune(c)):
+			return p.input[start:p.pos]
+		}
+	}
+
+	return p.input[start:p.pos]
+}